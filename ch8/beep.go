@@ -5,3 +5,16 @@ type Beep interface {
 	Play()
 	Pause()
 }
+
+// SampleBeep is a Beep that can also play the sampled sound of MegaChip. Beeps that do not implement it are silent
+// when a MegaChip program plays a sound.
+type SampleBeep interface {
+	Beep
+
+	// PlaySample plays 8-bit unsigned samples at the sample rate, once or in a loop until StopSample is called.
+	// A new sample replaces the one that is playing.
+	PlaySample(samples []byte, rate int, loop bool)
+
+	// StopSample stops the sample that is playing.
+	StopSample()
+}
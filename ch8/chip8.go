@@ -1,19 +1,21 @@
 package ch8
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"log"
-	"math/rand"
 	"os"
 	"path/filepath"
 )
 
 const (
-	instructionsPerFrame = 10
-	fps                  = 60
+	// FramesPerSecond is the rate of the frames, the timers and Tick.
+	FramesPerSecond = 60
 
 	// FrameDelay represents the time between two frames. It is used to time a 60hz loop.
-	FrameDelay = 1000 / fps
+	FrameDelay = 1000 / FramesPerSecond
 )
 
 // RenderingMode represents the different rendering modes of the super chip and xo-chip variants.
@@ -27,23 +29,65 @@ const (
 
 	// LoresRendering represents the lores rendering mode.
 	LoresRendering
+
+	// MegaRendering represents the 256x192 color display of MegaChip mode, which is drawn to the MegaBuffer
+	// returned by MegaDisplay instead of the DisplayBuffer.
+	MegaRendering
 )
 
+// largeFontAddress is the address of the large font of super-chip, after the small font.
+const largeFontAddress = 0x50
+
+// largeFontSet is the 8x10 digits of the large font of super-chip that FX30 points to.
+var largeFontSet = [100]byte{
+	0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C, // 0
+	0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, // 1
+	0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF, // 2
+	0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C, // 3
+	0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C, // 5
+	0x3E, 0x7C, 0xE0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C, // 6
+	0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60, // 7
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C, // 8
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C, // 9
+}
+
 // CPU represents the inner state of the Chip 8.
 type CPU struct {
-	Spec           Spec
+	Spec   Spec
+	Quirks Quirks
+
+	// Tickrate is the number of instructions run every frame. Fractions of an instruction are carried over to
+	// the next frame, so a tickrate of 7.5 runs 7 and 8 instructions in turns.
+	Tickrate float64
+
+	// VIPTiming runs the instructions for as long as they take on the COSMAC VIP instead of using the tickrate.
+	VIPTiming bool
+
 	registers      [16]byte
 	programCounter uint16
-	memory         [4096]byte
+	memory         []byte
 	stack          [16]uint16
 	stackPointer   uint
-	indexRegister  uint16
+	indexRegister  uint32
 	SoundTimer     byte
 	DelayTimer     byte
 
+	// flags is the user flags of the HP 48 that FX75 and FX85 save the registers to.
+	flags [16]byte
+
+	// startAddress is the address the program is loaded to and entryPoint the address it starts running at.
+	startAddress, entryPoint uint16
+
+	// colors is the state of the color board of CHIP-8X.
+	colors colorMap
+
+	// mega is the display of MegaChip mode. It is nil for the other specs.
+	mega *megaChip
+
 	// DisplayBuffer is a 2D array of booleans representing all the pixels in the Chip8 display.
 	// The size of the buffer is set to the hires mode of the super and xo-chip variants. Only use
-	// the 64x32 part when working with lores mode or original spec.
+	// the 64x32 part when working with lores mode or original spec, and the 64x64 part for hi-res CHIP-8.
 	// A true value represents an on pixel.
 	DisplayBuffer [64][128]bool
 
@@ -53,19 +97,47 @@ type CPU struct {
 
 	// DisplayUpdated a flag that is raised when the state of the DisplayBuffer is changed.
 	// Check this flag to redraw the screen only when necessary.
+	// DisplayBuffer, RenderingMode and DisplayUpdated can only be used by the goroutine that runs the cpu. Use
+	// Snapshot or ConsumeFrame from other goroutines.
 	DisplayUpdated bool
 
 	// Keypad is an array of 16 booleans representing the state of the 16 keys present in chip-8's keypad.
 	// ith index of this array represents the key of original chip-8 with the value i.
 	// For example, if Keypad[0xA] is true, the A button is pressed.
+	// Keypad can only be used by the goroutine that runs the cpu. Use PressKey and ReleaseKey from other goroutines.
 	Keypad [16]bool
 
+	// keyEdges is the keys pressed since the previous frame, and keyWait is the key FX0A waits for.
+	keyEdges keyEdges
+	keyWait  keyWait
+
+	// keysApplied is raised when the queued key events were applied to the keypad for the current frame, by a
+	// movie before Tick or by Tick itself.
+	keysApplied bool
+
+	// shared is the state that other goroutines can access while the cpu runs.
+	shared *shared
+
 	// beep is the sound played from the Chip8.
 	beep Beep
+
+	// random is the source of the random numbers used by the CXNN instruction.
+	random RandomSource
+
+	// romHash is the SHA-1 hash of the loaded program.
+	romHash string
+
+	// instructionBudget is the fraction of an instruction carried over from the previous frame.
+	instructionBudget float64
+
+	// cycleBudget is the number of machine cycles left in the frame with VIPTiming. It is negative when the last
+	// instruction of the previous frame ran past its end.
+	cycleBudget int
 }
 
-// NewCPU creates a new Chip8 with default values.
-func NewCPU(spec Spec, beep Beep) (ch8 CPU) {
+// NewCPU creates a new Chip8 with default values and the default quirks and tickrate of the spec. random is the source of the
+// random numbers, pass a source with a known seed to make the emulation deterministic.
+func NewCPU(spec Spec, beep Beep, random RandomSource) (ch8 CPU) {
 	fontSet := [80]byte{
 		0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
 		0x20, 0x60, 0x20, 0x20, 0x70, // 1
@@ -87,21 +159,38 @@ func NewCPU(spec Spec, beep Beep) (ch8 CPU) {
 
 	ch8 = CPU{
 		Spec:           spec,
-		programCounter: 0x200, // chip 8 programs are loaded from 512 bytes in.
+		Quirks:         spec.DefaultQuirks(),
+		Tickrate:       spec.DefaultTickrate(),
+		memory:         make([]byte, spec.memorySize()),
+		programCounter: spec.EntryPoint(),
+		startAddress:   spec.StartAddress(),
+		entryPoint:     spec.EntryPoint(),
+		colors:         newColorMap(),
 		beep:           beep,
 		RenderingMode:  LoresRendering,
+		random:         random,
+		shared:         newShared(LoresRendering),
 	}
 
 	for i := 0; i < len(fontSet); i++ {
 		ch8.memory[0x000+i] = fontSet[i] // addresses 0x000 to 0x080 reserved for fonts
 	}
 
+	if spec == MegaChip {
+		ch8.mega = newMegaChip()
+	}
+
+	if spec.hasLargeFont() {
+		copy(ch8.memory[largeFontAddress:], largeFontSet[:])
+	}
+
 	//ch8.memory[0x1FF] = 2 // value of 1-3 here will force the quirks test rom to bypass the menu screen
 
 	return
 }
 
-// LoadProgram reads a file from the provided path and loads its contents to the Chip8 memory.
+// LoadProgram reads a file from the provided path and loads its contents to the Chip8 memory. It returns an error
+// if the program does not fit in the memory of the spec.
 func (ch8 *CPU) LoadProgram(programPath string) error {
 	safePath := filepath.Clean(programPath)
 	file, err := os.Open(safePath)
@@ -121,157 +210,248 @@ func (ch8 *CPU) LoadProgram(programPath string) error {
 		return err
 	}
 
-	copy(ch8.memory[0x200:], buffer) // Load the program from 512 bytes in.
+	if room := len(ch8.memory) - int(ch8.startAddress); len(buffer) > room {
+		return fmt.Errorf("the rom is %d bytes, only %d bytes fit in the memory of the %s spec", len(buffer), room, ch8.Spec)
+	}
+
+	copy(ch8.memory[ch8.startAddress:], buffer)
+	ch8.romHash = HashROM(buffer)
 	return nil
 }
 
+// HashROM returns the hex encoded SHA-1 hash of a rom.
+func HashROM(rom []byte) string {
+	sum := sha1.Sum(rom)
+	return hex.EncodeToString(sum[:])
+}
+
+// ROMHash returns the hex encoded SHA-1 hash of the loaded program.
+func (ch8 *CPU) ROMHash() string {
+	return ch8.romHash
+}
+
 // ClearProgram clears the loaded program.
 func (ch8 *CPU) ClearProgram() {
-	for i := 0x200; i < len(ch8.memory); i++ {
+	for i := int(ch8.startAddress); i < len(ch8.memory); i++ {
 		ch8.memory[i] = 0x0
 	}
 
-	ch8.programCounter = 0x200
+	ch8.programCounter = ch8.entryPoint
 }
 
-// Tick emulates what the chip 8 does in 1/60 of a second.
-func (ch8 *CPU) Tick(speed int) {
+// Tick emulates what the chip 8 does in 1/60 of a second. The timers are decremented once per Tick whatever the
+// tickrate is. The keys pressed and released with PressKey and ReleaseKey are applied before the frame, and the
+// display is published for Snapshot and ConsumeFrame after it.
+func (ch8 *CPU) Tick() {
+	ch8.applyKeys()
+	ch8.keysApplied = false
+	ch8.keyEdges.update(ch8.Keypad)
+
 	if ch8.DelayTimer > 0 {
 		ch8.DelayTimer--
 	}
 
-	if ch8.SoundTimer > 0 {
+	sounding := ch8.SoundTimer > 0
+	if sounding {
 		ch8.beep.Play()
 		ch8.SoundTimer--
 	} else {
 		ch8.beep.Pause()
 	}
+	ch8.updateSound(sounding)
+
+	// DisplayUpdated is cleared by the caller, so it is saved to find out whether this frame changed the display.
+	updated := ch8.DisplayUpdated
+	ch8.DisplayUpdated = false
+
+	if ch8.VIPTiming {
+		ch8.emulateVIPFrame()
+	} else {
+		ch8.instructionBudget += ch8.Tickrate
+		instructions := int(ch8.instructionBudget)
+		ch8.instructionBudget -= float64(instructions)
+		ch8.emulateCycle(instructions)
+	}
 
-	ch8.emulateCycle(instructionsPerFrame, speed)
+	if ch8.DisplayUpdated {
+		ch8.publishFrame()
+	}
+	ch8.DisplayUpdated = ch8.DisplayUpdated || updated
 }
 
-func (ch8 *CPU) emulateCycle(cycles, speed int) {
-	runFor := cycles * speed
-	for i := 0; i < runFor; i++ {
-		opcode := ch8.readOpcode()
-		ch8.programCounter += 2
+func (ch8 *CPU) emulateCycle(instructions int) {
+	for i := 0; i < instructions; i++ {
+		if ch8.executeInstruction() {
+			return
+		}
+	}
+}
 
-		var (
-			c = byte((opcode & 0xF000) >> 12)
-			x = byte((opcode & 0x0F00) >> 8)
-			y = byte((opcode & 0x00F0) >> 4)
-			d = byte(opcode & 0x000F)
+// executeInstruction runs the instruction at the program counter. It returns true if the rest of the frame is
+// skipped, as when waiting for the vertical blank interrupt.
+func (ch8 *CPU) executeInstruction() (waitForInterrupt bool) {
+	opcode := ch8.readOpcode()
+	ch8.programCounter += 2
 
-			nnn = opcode & 0x0FFF
-			nn  = byte(opcode & 0x00FF)
-			n   = byte(opcode & 0x000F)
-		)
+	if ch8.Spec == MegaChip {
+		if handled, waitForInterrupt := ch8.executeMegaInstruction(opcode); handled {
+			return waitForInterrupt
+		}
+	}
 
-		switch c {
-		case 0x0:
-			switch y {
+	var (
+		c = byte((opcode & 0xF000) >> 12)
+		x = byte((opcode & 0x0F00) >> 8)
+		y = byte((opcode & 0x00F0) >> 4)
+		d = byte(opcode & 0x000F)
+
+		nnn = opcode & 0x0FFF
+		nn  = byte(opcode & 0x00FF)
+		n   = byte(opcode & 0x000F)
+	)
+
+	switch c {
+	case 0x0:
+		if opcode == 0x02A0 && ch8.Spec == Chip8X {
+			ch8.cycleBackground()
+			break
+		}
+		if opcode == 0x0230 && ch8.Spec == HiresChip8 {
+			// the interpreter patch of hi-res CHIP-8 clears both pages of the display with a machine code routine.
+			ch8.clearScreen()
+			break
+		}
+		switch y {
+		case 0xC:
+			ch8.scrollDown(n)
+		case 0xD:
+			ch8.scrollUp(n)
+		case 0xE:
+			switch d {
+			case 0x0:
+				ch8.clearScreen()
+			case 0xE:
+				ch8.returnFromSubroutine()
+			}
+		case 0xF:
+			switch d {
+			case 0xB:
+				ch8.scrollRight()
 			case 0xC:
-				ch8.scrollDownN(n)
+				ch8.scrollLeft()
+			case 0xD:
+				// exit interpreter
+				return true
 			case 0xE:
-				switch d {
-				case 0x0:
-					ch8.clearScreen()
-				case 0xE:
-					ch8.returnFromSubroutine()
-				}
+				ch8.switchToLores()
 			case 0xF:
-				switch d {
-				case 0xB:
-					ch8.scrollRightFour()
-				case 0xC:
-					ch8.scrollLeftFour()
-				case 0xD:
-					// exit interpreter
-					return
-				case 0xE:
-					ch8.switchToLores()
-				case 0xF:
-					ch8.switchToHires()
-				}
+				ch8.switchToHires()
 			}
+		}
+	case 0x1:
+		ch8.jump(nnn)
+	case 0x2:
+		ch8.call(nnn)
+	case 0x3:
+		ch8.skipIfEqualVxNn(x, nn)
+	case 0x4:
+		ch8.skipIfNotEqualVxNn(x, nn)
+	case 0x5:
+		if d == 0x1 && ch8.Spec == Chip8X {
+			ch8.addNibbles(x, y)
+			break
+		}
+		// maybe check if d == 0?
+		ch8.skipIfEqualVxVy(x, y)
+	case 0x6:
+		ch8.loadXNN(x, nn)
+	case 0x7:
+		ch8.addNnVx(x, nn)
+	case 0x8:
+		switch d {
+		case 0x0:
+			ch8.setVxVy(x, y)
 		case 0x1:
-			ch8.jump(nnn)
+			ch8.orVxVy(x, y)
 		case 0x2:
-			ch8.call(nnn)
+			ch8.andVxVy(x, y)
 		case 0x3:
-			ch8.skipIfEqualVxNn(x, nn)
+			ch8.xorVxVy(x, y)
 		case 0x4:
-			ch8.skipIfNotEqualVxNn(x, nn)
+			ch8.addVxVy(x, y)
 		case 0x5:
-			// maybe check if d == 0?
-			ch8.skipIfEqualVxVy(x, y)
+			ch8.subVxVy(x, y)
 		case 0x6:
-			ch8.loadXNN(x, nn)
+			ch8.rightShiftVx(x, y)
 		case 0x7:
-			ch8.addNnVx(x, nn)
-		case 0x8:
-			switch d {
-			case 0x0:
-				ch8.setVxVy(x, y)
-			case 0x1:
-				ch8.orVxVy(x, y)
-			case 0x2:
-				ch8.andVxVy(x, y)
-			case 0x3:
-				ch8.xorVxVy(x, y)
-			case 0x4:
-				ch8.addVxVy(x, y)
-			case 0x5:
-				ch8.subVxVy(x, y)
-			case 0x6:
-				ch8.rightShiftVx(x, y)
-			case 0x7:
-				ch8.subVyVx(y, x)
-			case 0xE:
-				ch8.leftShiftVx(x, y)
-			}
-		case 0x9:
-			ch8.skipIfNotEqualVxVy(x, y)
-		case 0xA:
-			ch8.loadIndexRegisterNNN(nnn)
-		case 0xB:
-			ch8.jumpWithOffset(nnn)
-		case 0xC:
-			ch8.randomAndNn(x, nn)
-		case 0xD:
-			ch8.draw(x, y, n)
+			ch8.subVyVx(y, x)
 		case 0xE:
-			switch nn {
-			case 0x9E:
-				ch8.skipIfVxPressed(x)
-			case 0xA1:
-				ch8.skipIfVxNotPressed(x)
-			}
-		case 0xF:
-			switch nn {
-			case 0x07:
-				ch8.setVxDelayTimer(x)
-			case 0x0A:
-				ch8.delayUntilKey(x)
-			case 0x15:
-				ch8.setDelayTimerVx(x)
-			case 0x18:
-				ch8.setSoundTimerVx(x)
-			case 0x1E:
-				ch8.addIndexVx(x)
-			case 0x29:
-				ch8.setIVx(x)
-			case 0x33:
-				ch8.vxToBCD(x)
-			case 0x55:
-				ch8.writeVxVi(x)
-			case 0x65:
-				ch8.writeViVx(x)
-			}
-		default:
-			log.Fatalf("Unimplemented opcode: %#x", opcode)
+			ch8.leftShiftVx(x, y)
+		}
+	case 0x9:
+		ch8.skipIfNotEqualVxVy(x, y)
+	case 0xA:
+		ch8.loadIndexRegisterNNN(nnn)
+	case 0xB:
+		if ch8.Spec == Chip8X {
+			ch8.setZoneColors(x, y, n)
+		} else {
+			ch8.jumpWithOffset(nnn)
 		}
+	case 0xC:
+		ch8.randomAndNn(x, nn)
+	case 0xD:
+		ch8.draw(x, y, n)
+		if ch8.Quirks.VBlank {
+			// wait for the vertical blank interrupt, which happens at the start of the next frame.
+			return true
+		}
+	case 0xE:
+		if (nn == 0xF2 || nn == 0xF5) && ch8.Spec == Chip8X {
+			ch8.skipIfSecondKeypad(nn == 0xF2)
+			break
+		}
+		switch nn {
+		case 0x9E:
+			ch8.skipIfVxPressed(x)
+		case 0xA1:
+			ch8.skipIfVxNotPressed(x)
+		}
+	case 0xF:
+		if (nn == 0xF8 || nn == 0xFB) && ch8.Spec == Chip8X {
+			// the tone generator and the input port of CHIP-8X are not emulated.
+			break
+		}
+		switch nn {
+		case 0x07:
+			ch8.setVxDelayTimer(x)
+		case 0x0A:
+			ch8.delayUntilKey(x)
+		case 0x15:
+			ch8.setDelayTimerVx(x)
+		case 0x18:
+			ch8.setSoundTimerVx(x)
+		case 0x1E:
+			ch8.addIndexVx(x)
+		case 0x29:
+			ch8.setIVx(x)
+		case 0x30:
+			ch8.setILargeVx(x)
+		case 0x33:
+			ch8.vxToBCD(x)
+		case 0x55:
+			ch8.writeVxVi(x)
+		case 0x65:
+			ch8.writeViVx(x)
+		case 0x75:
+			ch8.saveFlags(x)
+		case 0x85:
+			ch8.loadFlags(x)
+		}
+	default:
+		log.Fatalf("Unimplemented opcode: %#x", opcode)
 	}
+	return false
 }
 
 func (ch8 *CPU) readOpcode() (opcode uint16) {
@@ -340,28 +520,19 @@ func (ch8 *CPU) loadXNN(x, nn byte) {
 }
 
 func (ch8 *CPU) loadIndexRegisterNNN(nnn uint16) {
-	ch8.indexRegister = nnn
+	ch8.indexRegister = uint32(nnn)
 }
 
 func (ch8 *CPU) draw(x, y, n byte) {
-	if ch8.Spec == Original {
-		// TODO Display wait quirk
-	}
-
-	var xLimit, yLimit byte
-	switch ch8.RenderingMode {
-	case HiresRendering:
-		xLimit, yLimit = 128, 64
-	case LoresRendering:
-		xLimit, yLimit = 64, 32
-	}
+	width, height := ch8.Resolution()
+	xLimit, yLimit := byte(width), byte(height)
 
 	xCoordinate := ch8.registers[uint(x)] % xLimit
 	yCoordinate := ch8.registers[uint(y)] % yLimit
 	ch8.registers[0xF] = 0x0
 
 	for i := byte(0); i < n; i++ {
-		currentSpriteByte := ch8.memory[uint(ch8.indexRegister+uint16(i))]
+		currentSpriteByte := ch8.memory[ch8.address(ch8.indexRegister+uint32(i))]
 		for j := uint16(7); j <= 7; j-- { // start from 7
 			currentSpriteBit := (currentSpriteByte >> j) & 1
 			if currentSpriteBit == 1 && ch8.DisplayBuffer[uint(yCoordinate)][uint(xCoordinate)] {
@@ -373,25 +544,25 @@ func (ch8 *CPU) draw(x, y, n byte) {
 				ch8.DisplayUpdated = true
 			}
 
-			if ch8.Spec == Original || ch8.Spec == Super {
+			if ch8.Quirks.Wrap {
+				xCoordinate = (xCoordinate + 1) % xLimit
+			} else {
 				xCoordinate++
 				if xCoordinate >= xLimit {
 					break
 				}
-			} else {
-				xCoordinate = (xCoordinate + 1) % xLimit
 			}
 		}
 
 		xCoordinate = ch8.registers[uint(x)] % xLimit // reset x coordinate for the next row of sprites
 
-		if ch8.Spec == Original || ch8.Spec == Super {
+		if ch8.Quirks.Wrap {
+			yCoordinate = (yCoordinate + 1) % yLimit
+		} else {
 			yCoordinate++
 			if yCoordinate >= yLimit {
 				break
 			}
-		} else {
-			yCoordinate = (yCoordinate + 1) % yLimit
 		}
 	}
 }
@@ -412,14 +583,14 @@ func (ch8 *CPU) setVxVy(x, y byte) {
 
 func (ch8 *CPU) orVxVy(x, y byte) {
 	ch8.registers[uint(x)] |= ch8.registers[uint(y)]
-	if ch8.Spec == Original {
+	if ch8.Quirks.Logic {
 		ch8.registers[0xF] = 0x0
 	}
 }
 
 func (ch8 *CPU) andVxVy(x, y byte) {
 	ch8.registers[uint(x)] &= ch8.registers[uint(y)]
-	if ch8.Spec == Original {
+	if ch8.Quirks.Logic {
 		ch8.registers[0xF] = 0x0
 	}
 }
@@ -439,7 +610,7 @@ func (ch8 *CPU) subVxVy(x, y byte) {
 }
 
 func (ch8 *CPU) rightShiftVx(x, y byte) {
-	if ch8.Spec == Original {
+	if !ch8.Quirks.Shift {
 		ch8.registers[uint(x)] = ch8.registers[uint(y)]
 	}
 
@@ -462,7 +633,7 @@ func (ch8 *CPU) subVyVx(y, x byte) {
 }
 
 func (ch8 *CPU) leftShiftVx(x, y byte) {
-	if ch8.Spec == Original {
+	if !ch8.Quirks.Shift {
 		ch8.registers[uint(x)] = ch8.registers[uint(y)]
 	}
 
@@ -479,17 +650,17 @@ func (ch8 *CPU) skipIfNotEqualVxVy(x, y byte) {
 
 func (ch8 *CPU) jumpWithOffset(nnn uint16) {
 	var offset uint16
-	if ch8.Spec == Original {
-		offset = uint16(ch8.registers[0x0])
-	} else if ch8.Spec == Super {
+	if ch8.Quirks.Jump {
 		register := nnn & 0xF00 >> 8
 		offset = uint16(ch8.registers[register])
+	} else {
+		offset = uint16(ch8.registers[0x0])
 	}
 	ch8.programCounter = nnn + offset // take another look
 }
 
 func (ch8 *CPU) randomAndNn(x, nn byte) {
-	random := byte(rand.Intn(255))
+	random := ch8.random.RandomByte(ch8.memory)
 	ch8.registers[uint(x)] = random & nn
 }
 
@@ -519,66 +690,100 @@ func (ch8 *CPU) setSoundTimerVx(x byte) {
 	ch8.SoundTimer = ch8.registers[uint(x)]
 }
 
+// address returns the index in memory of an address computed from I. Addresses past the end of memory wrap around
+// to its start: I can point anywhere in the 16 MB of MegaChip, and FX1E can move it past the end of memory.
+func (ch8 *CPU) address(address uint32) int {
+	return int(address) % len(ch8.memory)
+}
+
 func (ch8 *CPU) addIndexVx(x byte) {
-	ch8.indexRegister += uint16(ch8.registers[uint(x)])
+	ch8.indexRegister += uint32(ch8.registers[uint(x)])
 }
 
 func (ch8 *CPU) writeVxVi(x byte) {
-	for i := uint16(0); i <= uint16(x); i++ {
-		ch8.memory[(uint(ch8.indexRegister + i))] = ch8.registers[uint(i)]
+	for i := uint32(0); i <= uint32(x); i++ {
+		ch8.memory[ch8.address(ch8.indexRegister+i)] = ch8.registers[uint(i)]
 	}
 
-	if ch8.Spec == Original {
-		ch8.indexRegister += uint16(x + 1)
-	}
+	ch8.incrementIndexAfterMemoryAccess(x)
 }
 
 func (ch8 *CPU) writeViVx(x byte) {
-	for i := uint16(0); i <= uint16(x); i++ {
-		ch8.registers[uint(i)] = ch8.memory[uint(ch8.indexRegister+i)]
+	for i := uint32(0); i <= uint32(x); i++ {
+		ch8.registers[uint(i)] = ch8.memory[ch8.address(ch8.indexRegister+i)]
 	}
 
-	if ch8.Spec == Original {
-		ch8.indexRegister += uint16(x + 1)
+	ch8.incrementIndexAfterMemoryAccess(x)
+}
+
+// incrementIndexAfterMemoryAccess increments the index register after FX55 and FX65 as the quirks describe.
+func (ch8 *CPU) incrementIndexAfterMemoryAccess(x byte) {
+	switch {
+	case ch8.Quirks.MemoryLeaveIUnchanged:
+	case ch8.Quirks.MemoryIncrementByX:
+		ch8.indexRegister += uint32(x)
+	default:
+		ch8.indexRegister += uint32(x) + 1
 	}
 }
 
 func (ch8 *CPU) xorVxVy(x, y byte) {
 	ch8.registers[uint(x)] ^= ch8.registers[uint(y)]
-	if ch8.Spec == Original {
+	if ch8.Quirks.Logic {
 		ch8.registers[0xF] = 0x0
 	}
 }
 
 func (ch8 *CPU) setIVx(x byte) {
 	character := ch8.registers[uint(x)&0xF] // only keep the lowest 4 bits
-	ch8.indexRegister = uint16(character) * 5
+	ch8.indexRegister = uint32(character) * 5
+}
+
+func (ch8 *CPU) setILargeVx(x byte) {
+	character := ch8.registers[uint(x)&0xF] // only keep the lowest 4 bits
+	ch8.indexRegister = largeFontAddress + uint32(character)*10
+}
+
+func (ch8 *CPU) saveFlags(x byte) {
+	copy(ch8.flags[:x+1], ch8.registers[:x+1])
+}
+
+func (ch8 *CPU) loadFlags(x byte) {
+	copy(ch8.registers[:x+1], ch8.flags[:x+1])
 }
 
 func (ch8 *CPU) vxToBCD(x byte) {
 	value := ch8.registers[uint(x)]
-	for i := uint16(2); i < 3; i-- {
+	for i := uint32(2); i < 3; i-- {
 		digit := value % 10
-		ch8.memory[uint(ch8.indexRegister+i)] = digit
+		ch8.memory[ch8.address(ch8.indexRegister+i)] = digit
 		value /= 10
 	}
 }
 
+// delayUntilKey waits for a key to be pressed and released and stores it in VX. The key has to go down while FX0A
+// waits, a key that is held from before does not count. The COSMAC VIP plays the beep while the key is held.
 func (ch8 *CPU) delayUntilKey(x byte) {
-	keyIsPressed := false
-	for keyIndex, key := range ch8.Keypad {
-		// The original variant did not continue execution until the key was released
-		if key {
-			ch8.memory[uint(x)] = byte(keyIndex)
-			keyIsPressed = true
-			break
+	if !ch8.keyWait.waiting {
+		key, pressed := ch8.keyEdges.takePressed()
+		if !pressed {
+			ch8.programCounter -= 2 // run FX0A again until a key is pressed, essentially pausing execution.
+			return
 		}
+		ch8.keyWait = keyWait{waiting: true, key: key}
 	}
 
-	// Decrement the program counter if no key is pressed, essentially pausing execution.
-	if !keyIsPressed {
+	key := ch8.keyWait.key
+	if ch8.Keypad[key] && !ch8.Quirks.WaitForPress {
+		if ch8.Spec.onVIP() && ch8.SoundTimer == 0 {
+			ch8.SoundTimer = 1
+		}
 		ch8.programCounter -= 2
+		return
 	}
+
+	ch8.registers[uint(x)] = key
+	ch8.keyWait = keyWait{}
 }
 
 func (ch8 *CPU) switchToLores() {
@@ -590,53 +795,3 @@ func (ch8 *CPU) switchToHires() {
 	ch8.RenderingMode = HiresRendering
 	ch8.DisplayUpdated = true
 }
-
-func (ch8 *CPU) scrollDownN(n byte) {
-	for y := len(ch8.DisplayBuffer) - 1; y >= 0; y-- {
-		for x, pixel := range ch8.DisplayBuffer[y] {
-			if pixel {
-				if y+int(n) < len(ch8.DisplayBuffer) {
-					ch8.DisplayBuffer[y][x] = false
-					ch8.DisplayBuffer[y+int(n)][x] = true
-				} else {
-					// Turn of the pixel that goes out of the display
-					ch8.DisplayBuffer[y][x] = false
-				}
-				ch8.DisplayUpdated = true
-			}
-		}
-	}
-}
-
-func (ch8 *CPU) scrollRightFour() {
-	for y, row := range ch8.DisplayBuffer {
-		for x := len(row) - 1; x >= 0; x-- {
-			if row[x] {
-				if x+4 < len(ch8.DisplayBuffer[y]) {
-					ch8.DisplayBuffer[y][x] = false
-					ch8.DisplayBuffer[y][x+4] = true
-				} else {
-					// Move pixel out of screen
-					ch8.DisplayBuffer[y][x] = true
-				}
-				ch8.DisplayUpdated = true
-			}
-		}
-	}
-}
-
-func (ch8 *CPU) scrollLeftFour() {
-	for y, row := range ch8.DisplayBuffer {
-		for x := 0; x < len(row); x++ {
-			if row[x] {
-				if x-4 < 0 {
-					// Pixel moves out of the screen
-					ch8.DisplayBuffer[y][x] = false
-				} else {
-					ch8.DisplayBuffer[y][x] = false
-					ch8.DisplayBuffer[y][x-4] = true
-				}
-			}
-		}
-	}
-}
@@ -0,0 +1,116 @@
+package ch8
+
+import (
+	"image/color"
+)
+
+// chip8XColors is the foreground colors of the color board of the COSMAC VIP, in the order of the color numbers
+// of BXYN.
+var chip8XColors = [8]color.RGBA{
+	{0x00, 0x00, 0x00, 0xFF}, // black
+	{0xFF, 0x00, 0x00, 0xFF}, // red
+	{0x00, 0x00, 0xFF, 0xFF}, // blue
+	{0xFF, 0x00, 0xFF, 0xFF}, // violet
+	{0x00, 0xFF, 0x00, 0xFF}, // green
+	{0xFF, 0xFF, 0x00, 0xFF}, // yellow
+	{0x00, 0xFF, 0xFF, 0xFF}, // aqua
+	{0xFF, 0xFF, 0xFF, 0xFF}, // white
+}
+
+// chip8XBackgrounds is the background colors of the color board in the order 02A0 steps through them.
+var chip8XBackgrounds = [4]color.RGBA{
+	{0x00, 0x00, 0x80, 0xFF}, // blue
+	{0x00, 0x00, 0x00, 0xFF}, // black
+	{0x00, 0x80, 0x00, 0xFF}, // green
+	{0x80, 0x00, 0x00, 0xFF}, // red
+}
+
+// chip8XDefaultColor is the color the foreground of every zone starts with.
+const chip8XDefaultColor = 1
+
+// ColorOverlay is the color of the pixels of CHIP-8X. The color board colors the foreground of the display in
+// zones 8 pixels wide, which can be as short as a single line.
+type ColorOverlay struct {
+	Background color.RGBA
+
+	// Foreground holds the color of the zone of every column of 8 pixels on every line of the display.
+	Foreground [32][8]color.RGBA
+}
+
+// At returns the foreground color of the pixel x, y of the lores display.
+func (o *ColorOverlay) At(x, y int) color.RGBA {
+	return o.Foreground[y%32][x/8%8]
+}
+
+// colorMap is the state of the color board: the color numbers of the zones and the background.
+type colorMap struct {
+	zones      [32][8]byte
+	background byte
+}
+
+// newColorMap creates the color map the color board starts with.
+func newColorMap() colorMap {
+	m := colorMap{}
+	for y := range m.zones {
+		for x := range m.zones[y] {
+			m.zones[y][x] = chip8XDefaultColor
+		}
+	}
+	return m
+}
+
+// ColorOverlay returns the colors of the display of CHIP-8X. It reports false for the other specs, which are
+// drawn with the colors of the frontend.
+func (ch8 *CPU) ColorOverlay() (ColorOverlay, bool) {
+	if ch8.Spec != Chip8X {
+		return ColorOverlay{}, false
+	}
+
+	overlay := ColorOverlay{Background: chip8XBackgrounds[ch8.colors.background]}
+	for y, row := range ch8.colors.zones {
+		for x, number := range row {
+			overlay.Foreground[y][x] = chip8XColors[number]
+		}
+	}
+	return overlay, true
+}
+
+// cycleBackground implements 02A0, which steps the background to the next color.
+func (ch8 *CPU) cycleBackground() {
+	ch8.colors.background = (ch8.colors.background + 1) % byte(len(chip8XBackgrounds))
+	ch8.DisplayUpdated = true
+}
+
+// setZoneColors implements BXYN, which colors zones of the display with the color in VY. The low nibbles of VX and
+// VX+1 are the column and the row of the first zone, and their high nibbles the number of zones added to the
+// right and below it. BXY0 colors zones 4 lines high, BXYN colors N lines starting at the line in VX+1.
+func (ch8 *CPU) setZoneColors(x, y, n byte) {
+	horizontal, vertical := ch8.registers[x], ch8.registers[(x+1)&0xF]
+	color := ch8.registers[y] & 7
+
+	firstLine, lines := int(vertical&0xF)*4, (int(vertical>>4)+1)*4
+	if n != 0 {
+		firstLine, lines = int(vertical), int(n)
+	}
+
+	for line := firstLine; line < firstLine+lines; line++ {
+		for column := int(horizontal & 0xF); column <= int(horizontal&0xF)+int(horizontal>>4); column++ {
+			ch8.colors.zones[line%32][column%8] = color
+		}
+	}
+	ch8.DisplayUpdated = true
+}
+
+// addNibbles implements 5XY1, which adds the 3-bit color and coordinate fields in the low 3 bits of the nibbles of
+// VY to those of VX. Bits 3 and 7 are cleared, so a field does not carry into the next one.
+func (ch8 *CPU) addNibbles(x, y byte) {
+	ch8.registers[x] = (ch8.registers[x]&0x77 + ch8.registers[y]&0x77) & 0x77
+}
+
+// skipIfSecondKeypad implements EXF2 and EXF5, which test the keys of the second keypad of CHIP-8X. Only one
+// keypad is emulated, so the keys of the second keypad are never pressed.
+func (ch8 *CPU) skipIfSecondKeypad(pressed bool) {
+	if !pressed {
+		ch8.programCounter += 2
+	}
+}
@@ -0,0 +1,23 @@
+package ch8
+
+import "testing"
+
+func TestSecondKeypadOnlyOnChip8X(t *testing.T) {
+	for name, spec := range Specs {
+		t.Run(name, func(t *testing.T) {
+			cpu := newTestCPU(spec)
+			start := cpu.programCounter
+
+			// EXF5 skips when the key of the second keypad is not pressed, which it never is.
+			execute(cpu, 0xE0F5)
+
+			want := start + 2
+			if spec == Chip8X {
+				want += 2
+			}
+			if cpu.programCounter != want {
+				t.Fatalf("pc = %03X after E0F5, want %03X", cpu.programCounter, want)
+			}
+		})
+	}
+}
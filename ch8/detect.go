@@ -0,0 +1,227 @@
+package ch8
+
+import "fmt"
+
+// Confidence represents how sure Detect is about its guess.
+type Confidence int
+
+const (
+	// LowConfidence means nothing specific to a spec was found, the guess is the default.
+	LowConfidence Confidence = iota
+
+	// MediumConfidence means the guess is based on a hint such as the size of the rom.
+	MediumConfidence
+
+	// HighConfidence means the rom runs instructions that only exist in the guessed spec.
+	HighConfidence
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case HighConfidence:
+		return "high"
+	case MediumConfidence:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// maxOriginalROMSize is the largest rom that fits in the 4K memory of the specs other than xo-chip and MegaChip.
+const maxOriginalROMSize = 0x1000 - 0x200
+
+// Detection is the result of Detect.
+type Detection struct {
+	Spec       Spec
+	Quirks     Quirks
+	Confidence Confidence
+
+	// Reasons describes the findings the guess is based on.
+	Reasons []string
+}
+
+// detector collects the findings about a rom.
+type detector struct {
+	rom []byte
+
+	// reachable marks the offsets of the instructions that can be reached from the entry point.
+	reachable map[int]bool
+
+	super, xo, mega     []string // instructions specific to super, xo-chip and MegaChip that are reachable
+	memoryIncrementUsed bool
+}
+
+// Detect guesses the spec and the quirks of a rom by analysing its instructions.
+// Instructions are followed from the entry point through jumps, calls and skips, so that sprites and other data
+// are not mistaken for instructions. Code that is only reached through BNNN is not analysed.
+func Detect(rom []byte) Detection {
+	d := detector{rom: rom, reachable: map[int]bool{}}
+
+	// hi-res CHIP-8 programs start with a jump into the patch of the interpreter, which is machine code.
+	hires := d.opcode(0) == 0x1260
+	if hires {
+		d.walk(int(HiresChip8.EntryPoint() - HiresChip8.StartAddress()))
+	} else {
+		d.walk(0)
+	}
+
+	detection := Detection{Spec: Original, Confidence: LowConfidence}
+	switch {
+	case hires:
+		detection.Spec, detection.Confidence = HiresChip8, MediumConfidence
+		detection.Reasons = append(detection.Reasons, "starts with 1260, the jump to the 64x64 display patch of hi-res CHIP-8")
+	case len(d.mega) > 0:
+		detection.Spec, detection.Confidence = MegaChip, HighConfidence
+		detection.Reasons = append(detection.Reasons, "runs MegaChip instructions: "+summary(d.mega))
+	case len(d.xo) > 0:
+		detection.Spec, detection.Confidence = Xo, HighConfidence
+		detection.Reasons = append(detection.Reasons, "runs xo-chip instructions: "+summary(d.xo))
+	case len(rom) > maxOriginalROMSize:
+		detection.Spec, detection.Confidence = Xo, MediumConfidence
+		detection.Reasons = append(detection.Reasons, fmt.Sprintf("the rom is %d bytes, only the 64K memory of xo-chip has room for more than %d", len(rom), maxOriginalROMSize))
+	case len(d.super) > 0:
+		detection.Spec, detection.Confidence = Super, HighConfidence
+		detection.Reasons = append(detection.Reasons, "runs super-chip instructions: "+summary(d.super))
+	default:
+		detection.Reasons = append(detection.Reasons, "no instructions specific to super-chip, xo-chip or MegaChip were found")
+	}
+
+	detection.Quirks = detection.Spec.DefaultQuirks()
+	if d.memoryIncrementUsed && detection.Quirks.MemoryLeaveIUnchanged {
+		// super-chip programs that need I to be incremented were written for CHIP-48, which increments it by X.
+		detection.Quirks.MemoryLeaveIUnchanged = false
+		detection.Quirks.MemoryIncrementByX = true
+		detection.Reasons = append(detection.Reasons, "uses I after FX55/FX65 without setting it, which needs I to be incremented")
+	}
+
+	return detection
+}
+
+// opcode returns the opcode at the offset of the rom, or 0 past its end.
+func (d *detector) opcode(offset int) uint16 {
+	if offset < 0 || offset+1 >= len(d.rom) {
+		return 0
+	}
+	return uint16(d.rom[offset])<<8 | uint16(d.rom[offset+1])
+}
+
+// walk follows the instructions starting at the offset.
+func (d *detector) walk(offset int) {
+	// after FX55 or FX65 on the current path, until I is set again.
+	afterMemoryAccess := false
+
+	for offset >= 0 && offset+1 < len(d.rom) && !d.reachable[offset] {
+		d.reachable[offset] = true
+		opcode := d.opcode(offset)
+		next := offset + 2
+
+		name, spec := specificInstruction(opcode)
+		switch spec {
+		case Super:
+			d.super = append(d.super, name)
+		case Xo:
+			d.xo = append(d.xo, name)
+		case MegaChip:
+			d.mega = append(d.mega, name)
+		}
+
+		if opcode == 0xF000 || opcode&0xFF00 == 0x0100 && len(d.mega) > 0 {
+			next += 2 // the address of F000 NNNN and 01NN NNNN takes 2 more bytes
+		}
+
+		switch {
+		case opcode&0xF0FF == 0xF055, opcode&0xF0FF == 0xF065:
+			if afterMemoryAccess {
+				d.memoryIncrementUsed = true
+			}
+			afterMemoryAccess = true
+		case opcode&0xF000 == 0xD000, opcode&0xF0FF == 0xF033:
+			if afterMemoryAccess {
+				d.memoryIncrementUsed = true
+			}
+		case opcode&0xF000 == 0xA000, opcode == 0xF000, opcode&0xFF00 == 0x0100, opcode&0xF0FF == 0xF01E,
+			opcode&0xF0FF == 0xF029, opcode&0xF0FF == 0xF030:
+			afterMemoryAccess = false
+		}
+
+		switch {
+		case opcode == 0x00EE, opcode == 0x00FD, opcode&0xF000 == 0xB000:
+			// the return address and the target of BNNN are not known statically.
+			return
+		case opcode&0xF000 == 0x1000:
+			next = int(opcode&0x0FFF) - 0x200
+			afterMemoryAccess = false
+		case opcode&0xF000 == 0x2000:
+			d.walk(int(opcode&0x0FFF) - 0x200)
+			afterMemoryAccess = false
+		case isSkip(opcode):
+			// the skipped instruction may be F000 NNNN on xo-chip, so both of its sizes are followed.
+			d.walk(next + 2)
+			d.walk(next + 4)
+		}
+
+		offset = next
+	}
+}
+
+// isSkip reports whether the opcode is a conditional skip.
+func isSkip(opcode uint16) bool {
+	switch opcode & 0xF000 {
+	case 0x3000, 0x4000:
+		return true
+	case 0x5000, 0x9000:
+		return opcode&0x000F == 0
+	case 0xE000:
+		return opcode&0x00FF == 0x9E || opcode&0x00FF == 0xA1
+	}
+	return false
+}
+
+// specificInstruction returns the name of the instruction and the spec it belongs to if it only exists in super,
+// xo-chip or MegaChip. The spec is Original for all the other instructions.
+func specificInstruction(opcode uint16) (string, Spec) {
+	switch {
+	case opcode == 0x0010, opcode == 0x0011:
+		return fmt.Sprintf("%04X", opcode), MegaChip
+
+	case opcode == 0x00FB, opcode == 0x00FC, opcode == 0x00FD, opcode == 0x00FE, opcode == 0x00FF:
+		return fmt.Sprintf("%04X", opcode), Super
+	case opcode&0xFFF0 == 0x00C0 && opcode != 0x00C0:
+		return "00CN", Super
+	case opcode&0xF000 == 0xD000 && opcode&0x000F == 0:
+		return "DXY0", Super
+	case opcode&0xF0FF == 0xF030:
+		return "FX30", Super
+	case opcode&0xF0FF == 0xF075, opcode&0xF0FF == 0xF085:
+		return fmt.Sprintf("FX%02X", opcode&0xFF), Super
+
+	case opcode == 0xF000:
+		return "F000", Xo
+	case opcode&0xFFF0 == 0x00D0 && opcode != 0x00D0:
+		return "00DN", Xo
+	case opcode&0xF00F == 0x5002, opcode&0xF00F == 0x5003:
+		return fmt.Sprintf("5XY%X", opcode&0xF), Xo
+	case opcode&0xF0FF == 0xF001 && opcode&0x0F00 <= 0x0300:
+		return "FN01", Xo
+	case opcode == 0xF002, opcode&0xF0FF == 0xF03A:
+		return fmt.Sprintf("FX%02X", opcode&0xFF), Xo
+	}
+	return "", Original
+}
+
+// summary returns the distinct names in the order they were found.
+func summary(names []string) string {
+	seen := map[string]bool{}
+	result := ""
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if result != "" {
+			result += ", "
+		}
+		result += name
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package ch8
+
+import (
+	"os"
+	"testing"
+)
+
+// rom writes the opcodes as the bytes of a rom.
+func rom(program ...uint16) []byte {
+	bytes := make([]byte, 0, 2*len(program))
+	for _, opcode := range program {
+		bytes = append(bytes, byte(opcode>>8), byte(opcode))
+	}
+	return bytes
+}
+
+func TestDetect(t *testing.T) {
+	superQuirks := Super.DefaultQuirks()
+	chip48Increment := superQuirks
+	chip48Increment.MemoryLeaveIUnchanged = false
+	chip48Increment.MemoryIncrementByX = true
+
+	tests := []struct {
+		name       string
+		rom        []byte
+		spec       Spec
+		quirks     Quirks
+		confidence Confidence
+	}{
+		{"original", rom(0x00E0, 0xA000, 0xD015, 0x1206), Original, Original.DefaultQuirks(), LowConfidence},
+		{"super", rom(0x00FF, 0x1202), Super, superQuirks, HighConfidence},
+		{"super scroll", rom(0x00C4, 0x1202), Super, superQuirks, HighConfidence},
+		{"super instruction in data", rom(0x1204, 0x00FF, 0x1204), Original, Original.DefaultQuirks(), LowConfidence},
+		{"xo", rom(0x00D1, 0x1202), Xo, Xo.DefaultQuirks(), HighConfidence},
+		{"xo over super", rom(0x00FF, 0xF000, 0x0300, 0x1206), Xo, Xo.DefaultQuirks(), HighConfidence},
+		{"megachip", rom(0x0011, 0x1202), MegaChip, MegaChip.DefaultQuirks(), HighConfidence},
+		{"hires", rom(0x1260), HiresChip8, HiresChip8.DefaultQuirks(), MediumConfidence},
+		{"rom size", make([]byte, maxOriginalROMSize+2), Xo, Xo.DefaultQuirks(), MediumConfidence},
+		{"rom size fits", make([]byte, maxOriginalROMSize), Original, Original.DefaultQuirks(), LowConfidence},
+		{"memory increment", rom(0x00FF, 0xA300, 0xF255, 0xF265, 0x1208), Super, chip48Increment, HighConfidence},
+		{"memory increment after I is set", rom(0x00FF, 0xA300, 0xF255, 0xA300, 0xF265, 0x120A), Super, superQuirks, HighConfidence},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			detection := Detect(test.rom)
+			if detection.Spec != test.spec || detection.Confidence != test.confidence {
+				t.Fatalf("detected %s with %s confidence, want %s with %s confidence (%v)",
+					detection.Spec, detection.Confidence, test.spec, test.confidence, detection.Reasons)
+			}
+			if detection.Quirks != test.quirks {
+				t.Fatalf("quirks = %+v, want %+v", detection.Quirks, test.quirks)
+			}
+		})
+	}
+}
+
+func TestDetectedSpecHasRoomForLargeROM(t *testing.T) {
+	large := make([]byte, maxOriginalROMSize+2)
+	path := t.TempDir() + "/rom.ch8"
+	if err := os.WriteFile(path, large, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cpu := newTestCPU(Detect(large).Spec)
+	if err := cpu.LoadProgram(path); err != nil {
+		t.Fatalf("the rom does not fit in the memory of the detected spec: %v", err)
+	}
+
+	cpu = newTestCPU(Original)
+	if err := cpu.LoadProgram(path); err == nil {
+		t.Fatal("a rom larger than the memory of the original spec was loaded")
+	}
+}
@@ -0,0 +1,97 @@
+package ch8
+
+import (
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+)
+
+// GIFRecorder records the display of the chip-8 to an animated GIF, one 60hz frame at a time.
+// A new image is only encoded when the display is updated, otherwise the previous image is held for
+// longer. GIF delays are in hundredths of a second, so the delays are rounded in a way that keeps the
+// total duration exact. Note that some viewers slow down images shown for less than 2/100 of a second.
+type GIFRecorder struct {
+	anim       gif.GIF
+	width      int
+	bg, fg     color.Color
+	frames     int // number of frames recorded
+	imageStart int // the frame the last image was encoded on
+	recording  bool
+}
+
+// NewGIFRecorder creates a GIFRecorder that records images of the given width using the provided colors.
+// The display is scaled by an integer factor to fit the width in both lores and hires modes.
+func NewGIFRecorder(width int, bg, fg color.Color) *GIFRecorder {
+	return &GIFRecorder{width: width, bg: bg, fg: fg}
+}
+
+// SetColors changes the colors of the images recorded from now on.
+func (r *GIFRecorder) SetColors(bg, fg color.Color) {
+	r.bg, r.fg = bg, fg
+}
+
+// Start discards any previously recorded images and starts recording.
+func (r *GIFRecorder) Start() {
+	r.anim = gif.GIF{}
+	r.frames = 0
+	r.imageStart = 0
+	r.recording = true
+}
+
+// Stop stops recording. The recorded images are kept until the next call to Start.
+func (r *GIFRecorder) Stop() {
+	r.finishImage()
+	r.recording = false
+}
+
+// Recording reports whether the recorder is currently recording.
+func (r *GIFRecorder) Recording() bool {
+	return r.recording
+}
+
+// RecordFrame records one frame of the cpu's display. Call it once per Tick, before DisplayUpdated is cleared.
+func (r *GIFRecorder) RecordFrame(cpu *CPU) {
+	if !r.recording {
+		return
+	}
+
+	if cpu.DisplayUpdated || len(r.anim.Image) == 0 {
+		r.finishImage()
+
+		width, _ := cpu.Resolution()
+		r.anim.Image = append(r.anim.Image, cpu.Image(max(1, r.width/width), r.bg, r.fg))
+		r.anim.Delay = append(r.anim.Delay, 0)
+		r.imageStart = r.frames
+	}
+
+	r.frames++
+}
+
+// finishImage sets the delay of the last encoded image to the time it has been displayed for.
+func (r *GIFRecorder) finishImage() {
+	last := len(r.anim.Delay) - 1
+	if last < 0 {
+		return
+	}
+
+	centiseconds := func(frames int) int {
+		return (frames*100 + FramesPerSecond/2) / FramesPerSecond
+	}
+	r.anim.Delay[last] = centiseconds(r.frames) - centiseconds(r.imageStart)
+}
+
+// SaveGIF writes the recorded images to a GIF file in the provided path.
+func (r *GIFRecorder) SaveGIF(path string) error {
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	if err := gif.EncodeAll(file, &r.anim); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
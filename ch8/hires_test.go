@@ -0,0 +1,85 @@
+package ch8
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHiresEntryPoint(t *testing.T) {
+	// the jump to the display patch, which is not loaded, and the program after it at 0x2C0.
+	program := make([]uint16, 0xC0/2)
+	program[0] = 0x1260
+	program = append(program, 0x6305, 0x12C2)
+
+	cpu := newTestCPU(HiresChip8, program...)
+	if cpu.programCounter != 0x2C0 {
+		t.Fatalf("pc = %03X, want 2C0", cpu.programCounter)
+	}
+
+	cpu.Tick()
+	if cpu.programCounter != 0x2C2 || cpu.registers[3] != 5 {
+		t.Fatalf("pc = %03X and V3 = %d, want 2C2 and 5", cpu.programCounter, cpu.registers[3])
+	}
+}
+
+func TestHiresClear(t *testing.T) {
+	tests := []struct {
+		spec    Spec
+		cleared bool
+	}{
+		{HiresChip8, true},
+		// 0230 calls machine code on the other specs, which is not emulated.
+		{Original, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec.String(), func(t *testing.T) {
+			cpu := newTestCPU(test.spec)
+			setPixels(cpu, [2]int{0, 0}, [2]int{63, 63})
+
+			execute(cpu, 0x0230)
+
+			if cleared := len(onPixels(cpu)) == 0; cleared != test.cleared || cpu.DisplayUpdated != test.cleared {
+				t.Fatalf("0230 left %v on, want the display cleared: %t", onPixels(cpu), test.cleared)
+			}
+		})
+	}
+}
+
+func TestHiresDraw(t *testing.T) {
+	tests := []struct {
+		name string
+		y    byte
+		wrap bool
+		rows []int
+	}{
+		{"bottom half", 40, false, []int{40, 41, 42, 43, 44}},
+		{"coordinate wraps", 70, false, []int{6, 7, 8, 9, 10}},
+		{"clipped", 62, false, []int{62, 63}},
+		{"sprite wraps", 62, true, []int{0, 1, 2, 62, 63}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(HiresChip8)
+			cpu.Quirks.Wrap = test.wrap
+			if width, height := cpu.Resolution(); width != 64 || height != 64 {
+				t.Fatalf("resolution = %dx%d, want 64x64", width, height)
+			}
+
+			// the 0 of the font, whose rows all set the first pixel.
+			cpu.registers[1] = test.y
+			execute(cpu, 0xD015)
+
+			var rows []int
+			for row := range cpu.DisplayBuffer {
+				if cpu.DisplayBuffer[row][0] {
+					rows = append(rows, row)
+				}
+			}
+			if !slices.Equal(rows, test.rows) {
+				t.Fatalf("drew the rows %v, want %v", rows, test.rows)
+			}
+		})
+	}
+}
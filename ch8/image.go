@@ -0,0 +1,112 @@
+package ch8
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Resolution returns the width and height of the visible part of the display buffer in the current rendering mode.
+func (ch8 *CPU) Resolution() (width, height int) {
+	switch ch8.RenderingMode {
+	case HiresRendering:
+		return 128, 64
+	case MegaRendering:
+		return megaWidth, megaHeight
+	}
+	if ch8.Spec == HiresChip8 {
+		return 64, 64
+	}
+	return 64, 32
+}
+
+// Image renders the visible part of the display buffer to an image using the provided colors. Every chip-8 pixel
+// becomes a scale x scale square in the image. CHIP-8X is rendered with the colors of its color overlay instead,
+// and MegaChip mode with its own colors, which are reduced to the Plan 9 palette.
+func (ch8 *CPU) Image(scale int, bg, fg color.Color) *image.Paletted {
+	width, height := ch8.Resolution()
+	if mega := ch8.MegaDisplay(); mega != nil {
+		return megaImage(mega, scale)
+	}
+
+	palette := color.Palette{bg, fg}
+	overlay, colored := ch8.ColorOverlay()
+	if colored {
+		palette = color.Palette{overlay.Background}
+		for _, c := range chip8XColors {
+			palette = append(palette, c)
+		}
+	}
+	img := image.NewPaletted(image.Rect(0, 0, width*scale, height*scale), palette)
+
+	for y := 0; y < height*scale; y++ {
+		for x := 0; x < width*scale; x++ {
+			if !ch8.DisplayBuffer[y/scale][x/scale] {
+				continue
+			}
+			if colored {
+				img.SetColorIndex(x, y, 1+ch8.colors.zones[y/scale%32][x/scale/8%8])
+			} else {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	return img
+}
+
+// megaImage renders the display of MegaChip mode over black to an image with the Plan 9 palette.
+func megaImage(mega *MegaBuffer, scale int) *image.Paletted {
+	plan9 := color.Palette(palette.Plan9)
+	img := image.NewPaletted(image.Rect(0, 0, megaWidth*scale, megaHeight*scale), plan9)
+
+	// a frame uses few colors, so the closest color of the palette is only searched once for each of them.
+	indices := map[color.NRGBA]uint8{}
+	for y := 0; y < megaHeight; y++ {
+		for x := 0; x < megaWidth; x++ {
+			c := mega[y][x]
+			index, ok := indices[c]
+			if !ok {
+				index = uint8(plan9.Index(color.RGBA{
+					R: uint8(uint16(c.R) * uint16(c.A) / 0xFF),
+					G: uint8(uint16(c.G) * uint16(c.A) / 0xFF),
+					B: uint8(uint16(c.B) * uint16(c.A) / 0xFF),
+					A: 0xFF,
+				}))
+				indices[c] = index
+			}
+
+			for sy := 0; sy < scale; sy++ {
+				for sx := 0; sx < scale; sx++ {
+					img.SetColorIndex(x*scale+sx, y*scale+sy, index)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// WritePNG encodes the visible part of the display buffer to w as a PNG image. See Image for the arguments.
+func (ch8 *CPU) WritePNG(w io.Writer, scale int, bg, fg color.Color) error {
+	return png.Encode(w, ch8.Image(scale, bg, fg))
+}
+
+// SavePNG saves the visible part of the display buffer to a PNG file in the provided path. See Image for the arguments.
+func (ch8 *CPU) SavePNG(path string, scale int, bg, fg color.Color) error {
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	if err := ch8.WritePNG(file, scale, bg, fg); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
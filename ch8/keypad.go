@@ -0,0 +1,41 @@
+package ch8
+
+// keyEdges tracks the keys that went down between two frames, for the instructions that wait for a key to be
+// pressed rather than test whether it is held. Edges are taken from the keypad once per frame, so a movie that
+// replays the keypad of every frame replays the edges as well.
+type keyEdges struct {
+	previous [16]bool
+
+	// pressed has bit i set when key i went down since the previous frame.
+	pressed uint16
+}
+
+// update finds the keys that went down since the keypad was last updated.
+func (e *keyEdges) update(keypad [16]bool) {
+	e.pressed = 0
+	for key, down := range keypad {
+		if down && !e.previous[key] {
+			e.pressed |= 1 << key
+		}
+	}
+	e.previous = keypad
+}
+
+// takePressed returns the lowest key that went down since the previous frame and clears its edge, so that every
+// press is only taken once. It reports false if no key went down.
+func (e *keyEdges) takePressed() (byte, bool) {
+	for key := byte(0); key < 16; key++ {
+		if e.pressed&(1<<key) != 0 {
+			e.pressed &^= 1 << key
+			return key, true
+		}
+	}
+	return 0, false
+}
+
+// keyWait is the state of FX0A while it waits for a key.
+type keyWait struct {
+	// waiting is raised when a key was pressed and FX0A waits for it to be released.
+	waiting bool
+	key     byte
+}
@@ -0,0 +1,162 @@
+package ch8
+
+import "testing"
+
+// waitForKey is FX0A with X = 3 followed by a jump to itself.
+var waitForKey = []uint16{0xF30A, 0x1202}
+
+func TestWaitForKeyStoresKeyInVX(t *testing.T) {
+	cpu := newTestCPU(Original, waitForKey...)
+	memory := cpu.memory[3]
+
+	cpu.PressKey(5)
+	cpu.Tick()
+	cpu.ReleaseKey(5)
+	cpu.Tick()
+
+	if cpu.registers[3] != 5 {
+		t.Fatalf("V3 = %d, want 5", cpu.registers[3])
+	}
+	if cpu.memory[3] != memory {
+		t.Fatal("FX0A wrote to memory")
+	}
+}
+
+func TestWaitForKeyWaitsForRelease(t *testing.T) {
+	cpu := newTestCPU(Original, waitForKey...)
+
+	cpu.PressKey(5)
+	for i := 0; i < 3; i++ {
+		cpu.Tick()
+		if cpu.programCounter != 0x200 {
+			t.Fatalf("FX0A continued on frame %d while the key is held", i)
+		}
+		if cpu.SoundTimer == 0 {
+			t.Fatalf("the original spec does not beep on frame %d while the key is held", i)
+		}
+	}
+
+	cpu.ReleaseKey(5)
+	cpu.Tick()
+	if cpu.programCounter != 0x202 || cpu.registers[3] != 5 {
+		t.Fatalf("FX0A did not continue with V3 = 5 after the release, pc = %03X and V3 = %d", cpu.programCounter, cpu.registers[3])
+	}
+}
+
+func TestWaitForKeyIgnoresKeyHeldBeforeWait(t *testing.T) {
+	// wait for the delay timer before FX0A.
+	cpu := newTestCPU(Original, 0x6A05, 0xFA15, 0xFA07, 0x3A00, 0x1204, 0xF30A, 0x120C)
+
+	cpu.PressKey(5)
+	for i := 0; i < 10; i++ {
+		cpu.Tick()
+	}
+	if cpu.programCounter != 0x20A {
+		t.Fatalf("pc = %03X, want FX0A at 20A", cpu.programCounter)
+	}
+
+	cpu.ReleaseKey(5)
+	cpu.Tick()
+	if cpu.programCounter != 0x20A {
+		t.Fatal("FX0A took a key that was held before it started waiting")
+	}
+
+	cpu.PressKey(7)
+	cpu.Tick()
+	cpu.ReleaseKey(7)
+	cpu.Tick()
+	if cpu.registers[3] != 7 {
+		t.Fatalf("V3 = %d, want 7", cpu.registers[3])
+	}
+}
+
+func TestWaitForKeyWaitForPressQuirk(t *testing.T) {
+	cpu := newTestCPU(Original, waitForKey...)
+	cpu.Quirks.WaitForPress = true
+
+	cpu.PressKey(5)
+	cpu.Tick()
+	if cpu.programCounter != 0x202 || cpu.registers[3] != 5 {
+		t.Fatalf("FX0A did not continue with V3 = 5 on the press, pc = %03X and V3 = %d", cpu.programCounter, cpu.registers[3])
+	}
+}
+
+func TestWaitForKeyTakesEveryPressOnce(t *testing.T) {
+	// F00A and F10A in a row.
+	cpu := newTestCPU(Original, 0xF00A, 0xF10A, 0x1204)
+	cpu.Quirks.WaitForPress = true
+
+	cpu.PressKey(5)
+	cpu.Tick()
+	if cpu.registers[0] != 5 || cpu.programCounter != 0x202 {
+		t.Fatalf("the first FX0A did not take the press, pc = %03X and V0 = %d", cpu.programCounter, cpu.registers[0])
+	}
+
+	cpu.ReleaseKey(5)
+	cpu.PressKey(6)
+	cpu.Tick()
+	if cpu.registers[1] != 6 {
+		t.Fatalf("V1 = %d, want 6 from the second press", cpu.registers[1])
+	}
+}
+
+func TestWaitForKeyQuickTap(t *testing.T) {
+	cpu := newTestCPU(Original, waitForKey...)
+
+	// a press and a release before the same frame, as a quick tap or a tap made while paused.
+	cpu.PressKey(5)
+	cpu.ReleaseKey(5)
+	cpu.Tick()
+	cpu.Tick()
+
+	if cpu.registers[3] != 5 {
+		t.Fatalf("V3 = %d, want 5", cpu.registers[3])
+	}
+}
+
+func TestWaitForKeyQuickTapWhileRecording(t *testing.T) {
+	cpu := newTestCPU(Original, waitForKey...)
+	movie := NewMovie(cpu, Settings{Spec: Original, Seed: 1})
+
+	cpu.PressKey(5)
+	cpu.ReleaseKey(5)
+	for i := 0; i < 2; i++ {
+		movie.RecordFrame(cpu)
+		cpu.Tick()
+	}
+
+	if cpu.registers[3] != 5 {
+		t.Fatalf("V3 = %d, want 5", cpu.registers[3])
+	}
+	if movie.Frames[0] != 1<<5 || movie.Frames[1] != 0 {
+		t.Fatalf("recorded frames %04X, want the tap held on the first frame", movie.Frames)
+	}
+}
+
+func TestWaitForKeyBeepsOnVIP(t *testing.T) {
+	tests := []struct {
+		spec Spec
+		beep bool
+	}{
+		{Original, true},
+		{HiresChip8, true},
+		{Chip8X, true},
+		{Super, false},
+		{Xo, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec.String(), func(t *testing.T) {
+			// F30A at the entry point, which runs again while it waits.
+			cpu := newTestCPU(test.spec)
+			cpu.memory[cpu.programCounter], cpu.memory[cpu.programCounter+1] = 0xF3, 0x0A
+			cpu.Quirks.WaitForPress = false
+
+			cpu.PressKey(5)
+			cpu.Tick()
+			if beep := cpu.SoundTimer > 0; beep != test.beep {
+				t.Fatalf("beeps while the key is held: %t, want %t", beep, test.beep)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package ch8
+
+import (
+	"image/color"
+)
+
+const (
+	// megaMemorySize is the 16 MB of memory of MegaChip, addressed by the 24 bits of 01NN NNNN.
+	megaMemorySize = 1 << 24
+
+	// megaWidth and megaHeight is the resolution of the display of MegaChip mode.
+	megaWidth, megaHeight = 256, 192
+
+	// megaSampleHeaderSize is the size of the header of a sound played by 060N: the sample rate in 2 bytes, the
+	// number of samples in 3 bytes and a reserved byte.
+	megaSampleHeaderSize = 6
+)
+
+// MegaBuffer is the display of MegaChip mode. The colors are not premultiplied by their alpha, a pixel that is not
+// fully opaque lets the black behind the display through.
+type MegaBuffer [megaHeight][megaWidth]color.NRGBA
+
+// blendMode is the way 080N blends the sprites of MegaChip mode with the display.
+type blendMode byte
+
+const (
+	blendNormal blendMode = iota
+	blend25
+	blend50
+	blendAdd
+	blendMultiply
+)
+
+// megaChip is the state of the display of MegaChip mode.
+type megaChip struct {
+	// palette is the colors loaded by 02NN. Color 0 is transparent.
+	palette [256]color.NRGBA
+
+	spriteWidth, spriteHeight int
+	screenAlpha               byte
+	blend                     blendMode
+	collisionColor            byte
+
+	// back is the buffer sprites are drawn to and front is the buffer that is shown. 00E0 shows the back buffer
+	// and clears it for the next frame.
+	back, front MegaBuffer
+
+	// indices is the palette index of every pixel of the back buffer, which collisions are found with.
+	indices [megaHeight][megaWidth]byte
+}
+
+// newMegaChip creates the display of MegaChip mode in the state MegaChip programs start with.
+func newMegaChip() *megaChip {
+	return &megaChip{spriteWidth: 8, spriteHeight: 8, screenAlpha: 0xFF}
+}
+
+// MegaDisplay returns the display of MegaChip mode as it was shown by the last 00E0. It returns nil when the cpu is
+// not in MegaChip mode, where DisplayBuffer is used instead.
+func (ch8 *CPU) MegaDisplay() *MegaBuffer {
+	if ch8.RenderingMode != MegaRendering {
+		return nil
+	}
+	return &ch8.mega.front
+}
+
+// executeMegaInstruction executes the instructions that MegaChip adds or changes. It reports whether the
+// instruction was handled and whether it ends the frame, like executeInstruction.
+func (ch8 *CPU) executeMegaInstruction(opcode uint16) (handled, waitForInterrupt bool) {
+	nn := byte(opcode)
+	megaMode := ch8.RenderingMode == MegaRendering
+
+	switch {
+	case opcode == 0x0010:
+		ch8.RenderingMode = LoresRendering
+		ch8.DisplayUpdated = true
+	case opcode == 0x0011:
+		ch8.mega = newMegaChip()
+		ch8.RenderingMode = MegaRendering
+		ch8.DisplayUpdated = true
+	case opcode&0xFFF0 == 0x00B0:
+		ch8.scroll(0, -int(opcode&0xF))
+	case opcode&0xFF00 == 0x0100:
+		// 01NN NNNN takes the low 16 bits of the address from the next 2 bytes.
+		ch8.indexRegister = uint32(nn)<<16 | uint32(ch8.readOpcode())
+		ch8.programCounter += 2
+	case opcode&0xFF00 == 0x0200:
+		ch8.loadMegaPalette(int(nn))
+	case opcode&0xFF00 == 0x0300:
+		ch8.mega.spriteWidth = megaSize(nn)
+	case opcode&0xFF00 == 0x0400:
+		ch8.mega.spriteHeight = megaSize(nn)
+	case opcode&0xFF00 == 0x0500:
+		ch8.mega.screenAlpha = nn
+	case opcode&0xFFF0 == 0x0600:
+		ch8.playSample(opcode&0xF == 0)
+	case opcode == 0x0700:
+		if beep, ok := ch8.beep.(SampleBeep); ok {
+			beep.StopSample()
+		}
+	case opcode&0xFFF0 == 0x0800:
+		ch8.mega.blend = blendMode(opcode & 0xF)
+	case opcode&0xFF00 == 0x0900:
+		ch8.mega.collisionColor = nn
+	case opcode == 0x00E0 && megaMode:
+		ch8.showMegaFrame()
+		return true, true
+	case opcode&0xF000 == 0xD000 && megaMode:
+		ch8.megaDraw(byte(opcode>>8&0xF), byte(opcode>>4&0xF))
+	default:
+		return false, false
+	}
+	return true, false
+}
+
+// megaSize returns the sprite size set by 03NN and 04NN, where 0 stands for 256.
+func megaSize(nn byte) int {
+	if nn == 0 {
+		return 256
+	}
+	return int(nn)
+}
+
+// megaByte returns the byte of memory at the address, which wraps around the end of memory like address.
+func (ch8 *CPU) megaByte(address int) byte {
+	return ch8.memory[ch8.address(uint32(address))]
+}
+
+// loadMegaPalette implements 02NN, which loads n colors from I to the palette, starting with color 1. Every color
+// is 4 bytes in the order alpha, red, green, blue.
+func (ch8 *CPU) loadMegaPalette(n int) {
+	for i := 0; i < n; i++ {
+		address := int(ch8.indexRegister) + i*4
+		ch8.mega.palette[i+1] = color.NRGBA{
+			R: ch8.megaByte(address + 1),
+			G: ch8.megaByte(address + 2),
+			B: ch8.megaByte(address + 3),
+			A: ch8.megaByte(address),
+		}
+	}
+}
+
+// playSample implements 060N, which plays the sound at I, once or in a loop. The sound is a header followed by
+// 8-bit unsigned samples. Beeps that can not play samples ignore it.
+func (ch8 *CPU) playSample(loop bool) {
+	beep, ok := ch8.beep.(SampleBeep)
+	if !ok {
+		return
+	}
+
+	i := int(ch8.indexRegister)
+	rate := int(ch8.megaByte(i))<<8 | int(ch8.megaByte(i+1))
+	length := int(ch8.megaByte(i+2))<<16 | int(ch8.megaByte(i+3))<<8 | int(ch8.megaByte(i+4))
+	start := i + megaSampleHeaderSize
+
+	samples := make([]byte, length)
+	for j := range samples {
+		samples[j] = ch8.megaByte(start + j)
+	}
+	beep.PlaySample(samples, rate, loop)
+}
+
+// showMegaFrame implements 00E0 in MegaChip mode, which shows the back buffer with the screen alpha and clears it
+// for the next frame.
+func (ch8 *CPU) showMegaFrame() {
+	m := ch8.mega
+	for y := range m.back {
+		for x, c := range m.back[y] {
+			c.A = byte(int(c.A) * int(m.screenAlpha) / 0xFF)
+			m.front[y][x] = c
+		}
+	}
+	m.back = MegaBuffer{}
+	m.indices = [megaHeight][megaWidth]byte{}
+	ch8.DisplayUpdated = true
+}
+
+// megaDraw implements DXYN in MegaChip mode. Sprites are the palette indices of the size set by 03NN and 04NN,
+// and are blended with the display instead of XORed. VF is set when a sprite covers a pixel of the collision
+// color. The characters of the fonts are still 1-bit sprites, which are drawn in white.
+func (ch8 *CPU) megaDraw(x, y byte) {
+	m := ch8.mega
+	startX, startY := int(ch8.registers[x]), int(ch8.registers[y])
+	ch8.registers[0xF] = 0
+
+	width, height := m.spriteWidth, m.spriteHeight
+	font := ch8.indexRegister < largeFontAddress+uint32(len(largeFontSet))
+	if font {
+		width, height = 8, 5
+		if ch8.indexRegister >= largeFontAddress {
+			height = 10
+		}
+	}
+
+	for row := 0; row < height; row++ {
+		for column := 0; column < width; column++ {
+			px, py := startX+column, startY+row
+			if px >= megaWidth || py >= megaHeight {
+				continue
+			}
+
+			var index byte
+			var c color.NRGBA
+			if font {
+				if ch8.megaByte(int(ch8.indexRegister)+row)&(0x80>>column) == 0 {
+					continue
+				}
+				index, c = 0xFF, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+			} else {
+				index = ch8.megaByte(int(ch8.indexRegister) + row*width + column)
+				if index == 0 {
+					continue
+				}
+				c = m.palette[index]
+			}
+
+			if m.indices[py][px] != 0 && m.indices[py][px] == m.collisionColor {
+				ch8.registers[0xF] = 1
+			}
+			m.back[py][px] = blend(m.back[py][px], c, m.blend)
+			m.indices[py][px] = index
+		}
+	}
+}
+
+// blend returns the color of a pixel of the display after a sprite pixel of color src is drawn over dst.
+func blend(dst, src color.NRGBA, mode blendMode) color.NRGBA {
+	alpha := float64(src.A) / 0xFF
+	switch mode {
+	case blend25:
+		alpha /= 4
+	case blend50:
+		alpha /= 2
+	}
+
+	// the colors are not premultiplied, so the color of dst only counts as much as dst is opaque.
+	dstAlpha := float64(dst.A) / 0xFF * (1 - alpha)
+	outAlpha := alpha + dstAlpha
+	if outAlpha == 0 {
+		return dst
+	}
+	channel := func(d, s uint8) uint8 {
+		switch mode {
+		case blendAdd:
+			return uint8(min(0xFF, float64(d)+float64(s)*alpha))
+		case blendMultiply:
+			return uint8(float64(d) * (1 - alpha + alpha*float64(s)/0xFF))
+		default:
+			return uint8((float64(s)*alpha + float64(d)*dstAlpha) / outAlpha)
+		}
+	}
+
+	return color.NRGBA{
+		R: channel(dst.R, src.R),
+		G: channel(dst.G, src.G),
+		B: channel(dst.B, src.B),
+		A: uint8(outAlpha * 0xFF),
+	}
+}
+
+// scroll moves the back buffer by dx pixels right and dy pixels down.
+func (m *megaChip) scroll(dx, dy int) {
+	back, indices := m.back, m.indices
+	for y := 0; y < megaHeight; y++ {
+		for x := 0; x < megaWidth; x++ {
+			fromX, fromY := x-dx, y-dy
+			if fromX >= 0 && fromX < megaWidth && fromY >= 0 && fromY < megaHeight {
+				m.back[y][x], m.indices[y][x] = back[fromY][fromX], indices[fromY][fromX]
+			} else {
+				m.back[y][x], m.indices[y][x] = color.NRGBA{}, 0
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package ch8
+
+import (
+	"image/color"
+	"testing"
+)
+
+// sampleRecorder is a SampleBeep that keeps the last sample it was asked to play.
+type sampleRecorder struct {
+	samples []byte
+	rate    int
+}
+
+func (r *sampleRecorder) Play()  {}
+func (r *sampleRecorder) Pause() {}
+
+func (r *sampleRecorder) PlaySample(samples []byte, rate int, _ bool) {
+	r.samples, r.rate = samples, rate
+}
+
+func (r *sampleRecorder) StopSample() {}
+
+// newMegaModeCPU creates a MegaChip cpu in MegaChip mode with I at the address.
+func newMegaModeCPU(i uint32) *CPU {
+	cpu := newTestCPU(MegaChip)
+	execute(cpu, 0x0011)
+	cpu.indexRegister = i
+	return cpu
+}
+
+func TestMegaChipPaletteWrapsAround(t *testing.T) {
+	cpu := newMegaModeCPU(megaMemorySize - 2)
+	copy(cpu.memory[megaMemorySize-2:], []byte{0xFF, 0x11})
+	copy(cpu.memory, []byte{0x22, 0x33, 0x80, 0x44, 0x55, 0x66})
+
+	execute(cpu, 0x0202)
+
+	want := []color.NRGBA{{R: 0x11, G: 0x22, B: 0x33, A: 0xFF}, {R: 0x44, G: 0x55, B: 0x66, A: 0x80}}
+	for i, c := range want {
+		if cpu.mega.palette[i+1] != c {
+			t.Fatalf("color %d = %v, want %v", i+1, cpu.mega.palette[i+1], c)
+		}
+	}
+}
+
+func TestMegaChipSpriteWrapsAround(t *testing.T) {
+	cpu := newMegaModeCPU(megaMemorySize - 1)
+	cpu.memory[megaMemorySize-1] = 3
+	cpu.memory[0] = 4
+
+	// a 2x1 sprite at 0, 0.
+	execute(cpu, 0x0302)
+	execute(cpu, 0x0401)
+	execute(cpu, 0xD010)
+
+	if cpu.mega.indices[0][0] != 3 || cpu.mega.indices[0][1] != 4 {
+		t.Fatalf("drew the colors %d and %d, want 3 and 4", cpu.mega.indices[0][0], cpu.mega.indices[0][1])
+	}
+}
+
+func TestMegaChipRegistersWrapAround(t *testing.T) {
+	cpu := newMegaModeCPU(megaMemorySize - 1)
+	for i := range cpu.registers {
+		cpu.registers[i] = byte(0xA0 + i)
+	}
+
+	execute(cpu, 0xFF55)
+	if cpu.memory[megaMemorySize-1] != 0xA0 || cpu.memory[0] != 0xA1 || cpu.memory[14] != 0xAF {
+		t.Fatalf("FF55 wrote %02X, %02X and %02X, want A0, A1 and AF",
+			cpu.memory[megaMemorySize-1], cpu.memory[0], cpu.memory[14])
+	}
+
+	cpu.registers = [16]byte{}
+	execute(cpu, 0xFF65)
+	for i, value := range cpu.registers {
+		if value != byte(0xA0+i) {
+			t.Fatalf("FF65 loaded V%X = %02X, want %02X", i, value, 0xA0+i)
+		}
+	}
+
+	cpu.registers[0] = 123
+	execute(cpu, 0xF033)
+	if cpu.memory[megaMemorySize-1] != 1 || cpu.memory[0] != 2 || cpu.memory[1] != 3 {
+		t.Fatalf("F033 wrote %d, %d and %d, want 1, 2 and 3", cpu.memory[megaMemorySize-1], cpu.memory[0], cpu.memory[1])
+	}
+}
+
+func TestMegaChipSampleWrapsAround(t *testing.T) {
+	// a sample of 3 bytes at the 2 last bytes of memory, after its header.
+	cpu := newTestCPU(MegaChip, 0x0011, 0x01FF, 0xFFF8, 0x0601)
+	beep := &sampleRecorder{}
+	cpu.beep = beep
+	header := []byte{0x1F, 0x40, 0x00, 0x00, 0x03, 0x00, 0xAA, 0xBB}
+	copy(cpu.memory[megaMemorySize-len(header):], header)
+	cpu.memory[0] = 0xCC
+
+	for cpu.programCounter < 0x208 {
+		cpu.executeInstruction()
+	}
+
+	if beep.rate != 8000 || string(beep.samples) != "\xAA\xBB\xCC" {
+		t.Fatalf("played %X at %d Hz, want AABBCC at 8000 Hz", beep.samples, beep.rate)
+	}
+}
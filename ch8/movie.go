@@ -0,0 +1,103 @@
+package ch8
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// movieVersion is the version of the movie file format. Movies of other versions can not be played back, as
+// the emulation they were recorded with may differ.
+const movieVersion = 1
+
+// Movie is a recording of the input of a chip-8 session. Emulation only depends on the program, the settings
+// and the state of the keypad on every frame, so a session can be reproduced bit for bit by playing a movie
+// back from power on.
+// Movies are stored as JSON files.
+type Movie struct {
+	Version int    `json:"version"`
+	ROMHash string `json:"romSha1"`
+	Settings
+
+	// Frames holds the state of the keypad for every frame. The ith bit is set if the key i is pressed.
+	Frames []uint16 `json:"frames"`
+}
+
+// NewMovie creates an empty movie for recording a session of the cpu, which is created with the given settings.
+// The program must be loaded before. The movie stores the tickrate of the cpu.
+func NewMovie(cpu *CPU, settings Settings) *Movie {
+	settings.Tickrate = cpu.Tickrate
+	return &Movie{
+		Version:  movieVersion,
+		ROMHash:  cpu.ROMHash(),
+		Settings: settings,
+	}
+}
+
+// LoadMovie reads a movie from the file in the provided path.
+func LoadMovie(path string) (*Movie, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	movie := &Movie{}
+	if err := json.Unmarshal(data, movie); err != nil {
+		return nil, err
+	}
+
+	if movie.Version != movieVersion {
+		return nil, fmt.Errorf("unsupported movie version %d", movie.Version)
+	}
+
+	return movie, nil
+}
+
+// SaveMovie writes the movie to a file in the provided path.
+func (m *Movie) SaveMovie(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Clean(path), data, 0o644)
+}
+
+// Check returns an error if the program loaded to the cpu is not the one the movie was recorded with.
+func (m *Movie) Check(cpu *CPU) error {
+	if cpu.ROMHash() != m.ROMHash {
+		return fmt.Errorf("the movie was recorded with a rom with the SHA-1 hash %s, not %s", m.ROMHash, cpu.ROMHash())
+	}
+	return nil
+}
+
+// RecordFrame appends the current state of the cpu's keypad to the movie. Call it once per frame, before Tick.
+// The keys pressed and released with PressKey and ReleaseKey are applied first, so that they are recorded on the
+// frame they are used on.
+func (m *Movie) RecordFrame(cpu *CPU) {
+	cpu.applyKeys()
+	var keys uint16
+	for i, pressed := range cpu.Keypad {
+		if pressed {
+			keys |= 1 << i
+		}
+	}
+	m.Frames = append(m.Frames, keys)
+}
+
+// PlayFrame sets the cpu's keypad to its state on the given frame. Call it once per frame, before Tick.
+// It returns false if the movie has no more frames. The keys pressed and released with PressKey and ReleaseKey are
+// overridden by the movie while it plays.
+func (m *Movie) PlayFrame(cpu *CPU, frame int) bool {
+	if frame >= len(m.Frames) {
+		return false
+	}
+
+	cpu.applyKeys()
+
+	for i := range cpu.Keypad {
+		cpu.Keypad[i] = m.Frames[frame]&(1<<i) != 0
+	}
+	return true
+}
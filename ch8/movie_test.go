@@ -0,0 +1,35 @@
+package ch8
+
+import "testing"
+
+func TestMoviePlaysQuickTapBack(t *testing.T) {
+	settings := Settings{Spec: Original, Seed: 1}
+	// wait for a key with FX0A and draw its character.
+	program := []uint16{0xF30A, 0xF329, 0xD005, 0x1206}
+
+	recorded := settings.NewCPU(NewAudioRecorder(nil))
+	loadOpcodes(&recorded, program...)
+	movie := NewMovie(&recorded, settings)
+
+	recorded.PressKey(5)
+	recorded.ReleaseKey(5)
+	for i := 0; i < 4; i++ {
+		movie.RecordFrame(&recorded)
+		recorded.Tick()
+	}
+	if recorded.programCounter != 0x206 {
+		t.Fatalf("pc = %03X, want 206 after the tap", recorded.programCounter)
+	}
+
+	played := movie.Settings.NewCPU(NewAudioRecorder(nil))
+	loadOpcodes(&played, program...)
+	for frame := 0; movie.PlayFrame(&played, frame); frame++ {
+		played.Tick()
+	}
+
+	if played.registers != recorded.registers || played.programCounter != recorded.programCounter ||
+		played.indexRegister != recorded.indexRegister || played.Keypad != recorded.Keypad ||
+		played.DisplayBuffer != recorded.DisplayBuffer {
+		t.Fatal("the movie does not reproduce the recorded session")
+	}
+}
@@ -0,0 +1,51 @@
+package ch8
+
+// Quirks represents the behaviours that differ between the variants of chip-8. The names of the quirks follow
+// the chip-8 database (https://github.com/chip-8/chip-8-database).
+type Quirks struct {
+	// Shift makes 8XY6 and 8XYE shift VX in place instead of shifting VY into VX.
+	Shift bool `json:"shift"`
+
+	// MemoryIncrementByX makes FX55 and FX65 increment I by X instead of X + 1.
+	MemoryIncrementByX bool `json:"memoryIncrementByX"`
+
+	// MemoryLeaveIUnchanged makes FX55 and FX65 leave I unchanged.
+	MemoryLeaveIUnchanged bool `json:"memoryLeaveIUnchanged"`
+
+	// Wrap makes sprites wrap around the edges of the screen instead of being clipped.
+	Wrap bool `json:"wrap"`
+
+	// Jump makes BNNN jump to XNN + VX instead of NNN + V0.
+	Jump bool `json:"jump"`
+
+	// VBlank makes DXYN wait for the vertical blank interrupt, so at most one sprite is drawn per frame.
+	VBlank bool `json:"vblank"`
+
+	// Logic makes 8XY1, 8XY2 and 8XY3 reset VF to 0.
+	Logic bool `json:"logic"`
+
+	// HalfPixelScroll makes 00CN, 00FB and 00FC scroll by half a pixel for every pixel in lores mode, like
+	// super-chip 1.1 on the HP 48, which draws lores pixels as 2x2 hires pixels and always scrolls by hires pixels.
+	// The lores display only holds whole pixels, so the half pixel of an odd distance is lost: 00C1 does not move
+	// the display and 00C3 moves it by 1 pixel.
+	HalfPixelScroll bool `json:"halfPixelScroll"`
+
+	// WaitForPress makes FX0A continue as soon as a key is pressed instead of when it is released.
+	WaitForPress bool `json:"waitForPress"`
+}
+
+// DefaultQuirks returns the quirks of the spec.
+func (s Spec) DefaultQuirks() Quirks {
+	switch s {
+	case Chip48, Super10:
+		return Quirks{Shift: true, MemoryIncrementByX: true, Jump: true}
+	case Super:
+		return Quirks{Shift: true, MemoryLeaveIUnchanged: true, Jump: true, HalfPixelScroll: true}
+	case SuperModern, MegaChip:
+		return Quirks{Shift: true, MemoryLeaveIUnchanged: true, Jump: true}
+	case Xo:
+		return Quirks{Wrap: true}
+	default:
+		return Quirks{VBlank: true, Logic: true}
+	}
+}
@@ -0,0 +1,47 @@
+package ch8
+
+import "math/rand"
+
+// RandomSource is the source of the random numbers generated by the CXNN instruction.
+type RandomSource interface {
+	// RandomByte returns the next random byte. memory is the memory of the cpu, some sources read from it.
+	RandomByte(memory []byte) byte
+}
+
+// mathRandomSource generates uniformly distributed bytes using math/rand.
+type mathRandomSource struct {
+	rng *rand.Rand
+}
+
+// NewMathRandomSource creates a RandomSource that generates uniformly distributed bytes from source.
+func NewMathRandomSource(source rand.Source) RandomSource {
+	return mathRandomSource{rng: rand.New(source)}
+}
+
+func (s mathRandomSource) RandomByte(_ []byte) byte {
+	return byte(s.rng.Intn(256))
+}
+
+// vipRandomSource generates random numbers with the algorithm of the random number routine of the COSMAC VIP
+// interpreter. It is not an exact emulation of the VIP: the numbers are not the ones a real VIP generates.
+// The interpreter keeps a 16 bit seed. On every call the seed is incremented, then the low byte of the seed is
+// used to read a byte from a page of memory, which is added to the high byte of the seed. The new high byte is
+// the random number. On a real VIP that page holds the interpreter itself, which is not in memory here, so the
+// first page of the program is read instead. The numbers have the character of the VIP routine, a short period
+// and a dependence on the contents of memory, but not its values.
+type vipRandomSource struct {
+	seed uint16
+}
+
+// NewVIPRandomSource creates a RandomSource that uses the algorithm of the random number routine of the COSMAC VIP.
+// The numbers are not the ones a real VIP generates.
+func NewVIPRandomSource(seed uint16) RandomSource {
+	return &vipRandomSource{seed: seed}
+}
+
+func (s *vipRandomSource) RandomByte(memory []byte) byte {
+	s.seed++
+	high := byte(s.seed>>8) + memory[0x200+int(byte(s.seed))]
+	s.seed = uint16(high)<<8 | s.seed&0xFF
+	return high
+}
@@ -0,0 +1,52 @@
+package ch8
+
+// scrollDown implements 00CN, which scrolls the display down by n pixels.
+func (ch8 *CPU) scrollDown(n byte) {
+	ch8.scroll(0, ch8.scrollDistance(int(n)))
+}
+
+// scrollUp implements the 00DN instruction of xo-chip, which scrolls the display up by n pixels.
+func (ch8 *CPU) scrollUp(n byte) {
+	ch8.scroll(0, -ch8.scrollDistance(int(n)))
+}
+
+// scrollRight implements 00FB, which scrolls the display right by 4 pixels.
+func (ch8 *CPU) scrollRight() {
+	ch8.scroll(ch8.scrollDistance(4), 0)
+}
+
+// scrollLeft implements 00FC, which scrolls the display left by 4 pixels.
+func (ch8 *CPU) scrollLeft() {
+	ch8.scroll(-ch8.scrollDistance(4), 0)
+}
+
+// scrollDistance returns the number of pixels of the current resolution a scroll by n pixels moves the display.
+// With the HalfPixelScroll quirk the scrolls of lores mode move by hires pixels like on the HP 48, which are half
+// a lores pixel. The lores display only holds whole pixels, so an odd distance loses its last half pixel.
+func (ch8 *CPU) scrollDistance(n int) int {
+	if ch8.Quirks.HalfPixelScroll && ch8.RenderingMode == LoresRendering {
+		return n / 2
+	}
+	return n
+}
+
+// scroll moves the visible part of the display by dx pixels right and dy pixels down. The pixels that move out of
+// the display are lost and the pixels that move in are off. In MegaChip mode the back buffer is scrolled, which is
+// shown by the next 00E0.
+func (ch8 *CPU) scroll(dx, dy int) {
+	if ch8.RenderingMode == MegaRendering {
+		ch8.mega.scroll(dx, dy)
+		return
+	}
+
+	width, height := ch8.Resolution()
+	scrolled := ch8.DisplayBuffer
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fromX, fromY := x-dx, y-dy
+			scrolled[y][x] = fromX >= 0 && fromX < width && fromY >= 0 && fromY < height && ch8.DisplayBuffer[fromY][fromX]
+		}
+	}
+	ch8.DisplayBuffer = scrolled
+	ch8.DisplayUpdated = true
+}
@@ -0,0 +1,98 @@
+package ch8
+
+import "testing"
+
+// execute runs a single instruction on the cpu.
+func execute(cpu *CPU, opcode uint16) {
+	cpu.memory[cpu.programCounter] = byte(opcode >> 8)
+	cpu.memory[cpu.programCounter+1] = byte(opcode)
+	cpu.executeInstruction()
+}
+
+// setPixels turns the pixels on and clears DisplayUpdated.
+func setPixels(cpu *CPU, pixels ...[2]int) {
+	for _, p := range pixels {
+		cpu.DisplayBuffer[p[1]][p[0]] = true
+	}
+	cpu.DisplayUpdated = false
+}
+
+// onPixels returns the pixels of the display buffer that are on.
+func onPixels(cpu *CPU) [][2]int {
+	var pixels [][2]int
+	for y, row := range cpu.DisplayBuffer {
+		for x, on := range row {
+			if on {
+				pixels = append(pixels, [2]int{x, y})
+			}
+		}
+	}
+	return pixels
+}
+
+func TestScroll(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   Spec
+		mode   RenderingMode
+		opcode uint16
+		before [][2]int
+		want   [][2]int
+	}{
+		{"down in lores", SuperModern, LoresRendering, 0x00C2, [][2]int{{3, 4}}, [][2]int{{3, 6}}},
+		{"down past the lores bottom", SuperModern, LoresRendering, 0x00C2, [][2]int{{3, 31}}, nil},
+		{"down in hires", SuperModern, HiresRendering, 0x00C2, [][2]int{{3, 31}}, [][2]int{{3, 33}}},
+		{"down past the hires bottom", SuperModern, HiresRendering, 0x00C2, [][2]int{{3, 63}}, nil},
+		{"up", Xo, LoresRendering, 0x00D3, [][2]int{{3, 4}}, [][2]int{{3, 1}}},
+		{"up past the top", Xo, LoresRendering, 0x00D3, [][2]int{{3, 2}}, nil},
+		{"right in lores", SuperModern, LoresRendering, 0x00FB, [][2]int{{10, 4}}, [][2]int{{14, 4}}},
+		{"right past the lores edge", SuperModern, LoresRendering, 0x00FB, [][2]int{{61, 4}}, nil},
+		{"right in hires", SuperModern, HiresRendering, 0x00FB, [][2]int{{61, 4}}, [][2]int{{65, 4}}},
+		{"right past the hires edge", SuperModern, HiresRendering, 0x00FB, [][2]int{{125, 4}}, nil},
+		{"left", SuperModern, LoresRendering, 0x00FC, [][2]int{{10, 4}}, [][2]int{{6, 4}}},
+		{"left past the edge", SuperModern, LoresRendering, 0x00FC, [][2]int{{2, 4}}, nil},
+		{"half pixels in lores", Super, LoresRendering, 0x00FB, [][2]int{{10, 4}}, [][2]int{{12, 4}}},
+		{"half pixels down in lores", Super, LoresRendering, 0x00C4, [][2]int{{3, 4}}, [][2]int{{3, 6}}},
+		{"odd half pixels in lores", Super, LoresRendering, 0x00C3, [][2]int{{3, 4}}, [][2]int{{3, 5}}},
+		{"a single half pixel in lores", Super, LoresRendering, 0x00C1, [][2]int{{3, 4}}, [][2]int{{3, 4}}},
+		{"half pixels in hires", Super, HiresRendering, 0x00FB, [][2]int{{10, 4}}, [][2]int{{14, 4}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(test.spec)
+			cpu.RenderingMode = test.mode
+			setPixels(cpu, test.before...)
+
+			execute(cpu, test.opcode)
+
+			got := onPixels(cpu)
+			if len(got) != len(test.want) || len(got) == 1 && got[0] != test.want[0] {
+				t.Errorf("pixels %v, want %v", got, test.want)
+			}
+			if !cpu.DisplayUpdated {
+				t.Error("DisplayUpdated is not set")
+			}
+		})
+	}
+}
+
+func TestScrollPixelsMovingInAreOff(t *testing.T) {
+	cpu := newTestCPU(SuperModern)
+	for x := 0; x < 64; x++ {
+		setPixels(cpu, [2]int{x, 0})
+	}
+
+	execute(cpu, 0x00FB)
+
+	for x := 0; x < 64; x++ {
+		if on := cpu.DisplayBuffer[0][x]; on != (x >= 4) {
+			t.Errorf("pixel %d is %t after 00FB", x, on)
+		}
+	}
+	for x := 64; x < 128; x++ {
+		if cpu.DisplayBuffer[0][x] {
+			t.Errorf("pixel %d outside the lores display is on after 00FB", x)
+		}
+	}
+}
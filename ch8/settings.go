@@ -0,0 +1,40 @@
+package ch8
+
+import "math/rand"
+
+// Settings holds everything other than the program and the input that affects how a session is emulated.
+// Movies store the settings they were recorded with, so they can be played back exactly.
+type Settings struct {
+	Spec   Spec   `json:"spec"`
+	Quirks Quirks `json:"quirks"`
+
+	// Tickrate is the number of instructions run every frame. The default tickrate of the spec is used if it is 0.
+	Tickrate float64 `json:"tickrate"`
+	Seed     int64   `json:"seed"`
+
+	// VIPTiming runs the instructions for as long as they take on the COSMAC VIP instead of using the tickrate.
+	VIPTiming bool `json:"vipTiming,omitempty"`
+
+	// VIPRandom selects the algorithm of the COSMAC VIP's random number routine instead of math/rand for the CXNN
+	// instruction. It does not generate the same numbers as a real VIP.
+	VIPRandom bool `json:"vipRandom,omitempty"`
+}
+
+// NewCPU creates a cpu with the spec, quirks, timing and random source described by the settings.
+func (s Settings) NewCPU(beep Beep) CPU {
+	cpu := NewCPU(s.Spec, beep, s.RandomSource())
+	cpu.Quirks = s.Quirks
+	if s.Tickrate > 0 {
+		cpu.Tickrate = s.Tickrate
+	}
+	cpu.VIPTiming = s.VIPTiming
+	return cpu
+}
+
+// RandomSource creates the random source described by the settings.
+func (s Settings) RandomSource() RandomSource {
+	if s.VIPRandom {
+		return NewVIPRandomSource(uint16(s.Seed))
+	}
+	return NewMathRandomSource(rand.NewSource(s.Seed))
+}
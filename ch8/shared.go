@@ -0,0 +1,182 @@
+package ch8
+
+import (
+	"sync"
+)
+
+// Event is a notification sent by the cpu to the channels returned by Subscribe.
+type Event byte
+
+const (
+	_ Event = iota
+
+	// FrameReady is sent at the end of a Tick that changed the display. The frame can be read with Snapshot or
+	// ConsumeFrame.
+	FrameReady
+
+	// SoundStarted is sent when the sound timer starts the beep.
+	SoundStarted
+
+	// SoundStopped is sent when the sound timer runs out and the beep stops.
+	SoundStopped
+)
+
+// Frame is a copy of the display of the cpu at the end of a Tick.
+type Frame struct {
+	DisplayBuffer [64][128]bool
+	RenderingMode RenderingMode
+
+	// Mega is a copy of the display of MegaChip mode, which is nil in the other modes.
+	Mega *MegaBuffer
+}
+
+// keyEvent is a change of the state of a keypad key made with PressKey or ReleaseKey.
+type keyEvent struct {
+	key     byte
+	pressed bool
+}
+
+// shared is the state of the cpu that other goroutines can access while the cpu runs. It is kept behind a pointer
+// so that the cpu can still be copied.
+type shared struct {
+	mutex sync.Mutex
+
+	// keys is the key events waiting to be applied to the keypad.
+	keys []keyEvent
+
+	frame Frame
+
+	// fresh is raised when the frame has not been consumed yet.
+	fresh bool
+
+	listeners []chan Event
+
+	// sounding is raised while the beep plays. It is only used by the goroutine that runs the cpu.
+	sounding bool
+}
+
+// newShared creates the shared state of a cpu whose display starts with the provided rendering mode.
+func newShared(renderingMode RenderingMode) *shared {
+	return &shared{frame: Frame{RenderingMode: renderingMode}}
+}
+
+// PressKey presses a key of the keypad. The key is pressed when the next frame starts. It is safe to call from any
+// goroutine, unlike writing to Keypad.
+func (ch8 *CPU) PressKey(key byte) {
+	ch8.queueKey(key, true)
+}
+
+// ReleaseKey releases a key of the keypad. The key is released when the next frame starts. It is safe to call from
+// any goroutine, unlike writing to Keypad.
+func (ch8 *CPU) ReleaseKey(key byte) {
+	ch8.queueKey(key, false)
+}
+
+func (ch8 *CPU) queueKey(key byte, pressed bool) {
+	s := ch8.shared
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys = append(s.keys, keyEvent{key: key & 0xF, pressed: pressed})
+}
+
+// applyKeys applies the key events queued by PressKey and ReleaseKey to the keypad. A key changes at most once per
+// frame, the later events of a key that already changed stay queued for the next frames. A key that is pressed and
+// released before a frame starts is held for one frame, so that the press is not lost and a movie records it.
+// The events are only applied once per frame, so Tick does not apply them again after a movie recorded or
+// replaced the keypad.
+func (ch8 *CPU) applyKeys() {
+	if ch8.keysApplied {
+		return
+	}
+	ch8.keysApplied = true
+
+	s := ch8.shared
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var changed uint16
+	queued := s.keys[:0]
+	for _, event := range s.keys {
+		if changed&(1<<event.key) != 0 {
+			queued = append(queued, event)
+			continue
+		}
+		if ch8.Keypad[event.key] != event.pressed {
+			ch8.Keypad[event.key] = event.pressed
+			changed |= 1 << event.key
+		}
+	}
+	s.keys = queued
+}
+
+// Snapshot returns the display as it was at the end of the last Tick that changed it. It is safe to call from any
+// goroutine, unlike reading DisplayBuffer.
+func (ch8 *CPU) Snapshot() Frame {
+	s := ch8.shared
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.frame
+}
+
+// ConsumeFrame returns the display like Snapshot, and reports whether it changed since the last call to
+// ConsumeFrame. It is safe to call from any goroutine, unlike clearing DisplayUpdated.
+func (ch8 *CPU) ConsumeFrame() (Frame, bool) {
+	s := ch8.shared
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fresh := s.fresh
+	s.fresh = false
+	return s.frame, fresh
+}
+
+// Subscribe returns a channel that receives the events of the cpu. The cpu never waits for a subscriber, events
+// that do not fit in the buffer of the channel are dropped. It is safe to call from any goroutine.
+func (ch8 *CPU) Subscribe(buffer int) <-chan Event {
+	s := ch8.shared
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	listener := make(chan Event, buffer)
+	s.listeners = append(s.listeners, listener)
+	return listener
+}
+
+// publishFrame copies the display for the other goroutines and notifies the subscribers.
+func (ch8 *CPU) publishFrame() {
+	s := ch8.shared
+	s.mutex.Lock()
+	s.frame = Frame{DisplayBuffer: ch8.DisplayBuffer, RenderingMode: ch8.RenderingMode}
+	if mega := ch8.MegaDisplay(); mega != nil {
+		copied := *mega
+		s.frame.Mega = &copied
+	}
+	s.fresh = true
+	s.mutex.Unlock()
+	ch8.notify(FrameReady)
+}
+
+// updateSound notifies the subscribers when the beep starts or stops.
+func (ch8 *CPU) updateSound(sounding bool) {
+	s := ch8.shared
+	if sounding == s.sounding {
+		return
+	}
+	s.sounding = sounding
+	if sounding {
+		ch8.notify(SoundStarted)
+	} else {
+		ch8.notify(SoundStopped)
+	}
+}
+
+// notify sends an event to every subscriber that has room for it.
+func (ch8 *CPU) notify(event Event) {
+	s := ch8.shared
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, listener := range s.listeners {
+		select {
+		case listener <- event:
+		default:
+		}
+	}
+}
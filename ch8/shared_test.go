@@ -0,0 +1,126 @@
+package ch8
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// newTestCPU creates a cpu of the spec with the program loaded, written as opcodes.
+func newTestCPU(spec Spec, program ...uint16) *CPU {
+	cpu := NewCPU(spec, NewAudioRecorder(nil), NewMathRandomSource(rand.NewSource(1)))
+	loadOpcodes(&cpu, program...)
+	return &cpu
+}
+
+// loadOpcodes writes the program to the memory of the cpu from its start address.
+func loadOpcodes(cpu *CPU, program ...uint16) {
+	for i, opcode := range program {
+		cpu.memory[int(cpu.startAddress)+2*i] = byte(opcode >> 8)
+		cpu.memory[int(cpu.startAddress)+2*i+1] = byte(opcode)
+	}
+}
+
+func TestPressKeyAppliesOnTick(t *testing.T) {
+	cpu := newTestCPU(Original, 0x1200)
+
+	cpu.PressKey(5)
+	if cpu.Keypad[5] {
+		t.Fatal("the key is pressed before the frame starts")
+	}
+	cpu.Tick()
+	if !cpu.Keypad[5] {
+		t.Fatal("the key is not pressed after Tick")
+	}
+
+	cpu.ReleaseKey(5)
+	cpu.Tick()
+	if cpu.Keypad[5] {
+		t.Fatal("the key is not released after Tick")
+	}
+}
+
+func TestEvents(t *testing.T) {
+	// set the sound timer to 2 and draw the 0 of the font.
+	cpu := newTestCPU(Original, 0x6302, 0xF318, 0xA000, 0xD015, 0x1208)
+	events := cpu.Subscribe(8)
+
+	for i := 0; i < 4; i++ {
+		cpu.Tick()
+	}
+
+	var got []Event
+	for len(events) > 0 {
+		got = append(got, <-events)
+	}
+	want := map[Event]bool{FrameReady: true, SoundStarted: true, SoundStopped: true}
+	for _, event := range got {
+		delete(want, event)
+	}
+	if len(want) != 0 {
+		t.Fatalf("got events %v, missing %v", got, want)
+	}
+
+	frame, fresh := cpu.ConsumeFrame()
+	if !fresh || !frame.DisplayBuffer[0][0] {
+		t.Fatal("ConsumeFrame does not return the drawn frame")
+	}
+	if _, fresh := cpu.ConsumeFrame(); fresh {
+		t.Fatal("a consumed frame is fresh again")
+	}
+}
+
+// TestConcurrentAccess uses the accessors from other goroutines while the cpu runs. Run it with -race.
+func TestConcurrentAccess(t *testing.T) {
+	// draw the 0 of the font over and over, reading the keypad in between.
+	cpu := newTestCPU(Original, 0xA000, 0xD015, 0xE09E, 0x1200, 0x1200)
+	events := cpu.Subscribe(1)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			cpu.Tick()
+		}
+	}()
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			cpu.PressKey(byte(i))
+			cpu.ReleaseKey(byte(i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			cpu.Snapshot()
+			cpu.ConsumeFrame()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			cpu.Subscribe(1)
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-events:
+			}
+		}
+	}()
+
+	wg.Wait()
+}
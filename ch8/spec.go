@@ -16,6 +16,27 @@ const (
 
 	// Xo represents the XO-Chip spec of Chip 8.
 	Xo
+
+	// Chip48 represents CHIP-48, the first chip-8 interpreter of the HP 48.
+	Chip48
+
+	// Super10 represents version 1.0 of Super-chip8, which added hires mode to CHIP-48.
+	Super10
+
+	// SuperModern represents the Super-chip8 of modern interpreters such as Octo and SCHIP-C, which scroll and
+	// draw like the original but do not share its quirks.
+	SuperModern
+
+	// Chip8X represents CHIP-8X, the interpreter of the COSMAC VIP with the color board.
+	Chip8X
+
+	// MegaChip represents MegaChip8, which extends super-chip with a 256x192 color display, a 16 MB address
+	// space and sampled sound.
+	MegaChip
+
+	// HiresChip8 represents the hi-res CHIP-8 interpreter of the COSMAC VIP, which shows 64x64 pixels on two pages
+	// of display memory.
+	HiresChip8
 )
 
 // Specs maps the names of each spec to its corresponding Spec value.
@@ -23,15 +44,110 @@ var Specs = map[string]Spec{
 	"original": Original,
 	"super":    Super,
 	"xo":       Xo,
+	"chip48":   Chip48,
+	"super10":  Super10,
+	"superc":   SuperModern,
+	"chip8x":   Chip8X,
+	"megachip": MegaChip,
+	"hires":    HiresChip8,
+}
+
+// String returns the name of the spec as used in the Specs map.
+func (s Spec) String() string {
+	for name, spec := range Specs {
+		if spec == s {
+			return name
+		}
+	}
+	return fmt.Sprintf("Spec(%d)", int(s))
+}
+
+// MarshalText encodes the spec as its name.
+func (s Spec) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a spec from its name.
+func (s *Spec) UnmarshalText(text []byte) error {
+	spec, ok := Specs[string(text)]
+	if !ok {
+		return fmt.Errorf("unknown spec %q", text)
+	}
+	*s = spec
+	return nil
+}
+
+// DefaultTickrate returns the number of instructions run every frame by default for the spec. The interpreters
+// of the COSMAC VIP ran roughly 15 instructions per frame, the interpreters of the HP 48 ran faster and XO-Chip
+// programs are written for the much higher speed of Octo.
+func (s Spec) DefaultTickrate() float64 {
+	switch s {
+	case Super, Chip48, Super10, SuperModern:
+		return 30
+	case Xo:
+		return 100
+	case MegaChip:
+		return 1000
+	default:
+		return 15
+	}
+}
+
+// StartAddress returns the address programs are loaded to. CHIP-8X programs start after the larger interpreter
+// of the color board, the other specs start at 0x200.
+func (s Spec) StartAddress() uint16 {
+	if s == Chip8X {
+		return 0x300
+	}
+	return 0x200
+}
+
+// EntryPoint returns the address the program starts running at. Hi-res CHIP-8 programs start with the patch of the
+// interpreter that adds the 64x64 display, which is emulated natively, so they start running at 0x2C0 after it.
+// The other specs start running at the address the program is loaded to.
+func (s Spec) EntryPoint() uint16 {
+	if s == HiresChip8 {
+		return 0x2C0
+	}
+	return s.StartAddress()
+}
+
+// onVIP reports whether the spec is an interpreter of the COSMAC VIP.
+func (s Spec) onVIP() bool {
+	switch s {
+	case Original, HiresChip8, Chip8X:
+		return true
+	}
+	return false
+}
+
+// hasLargeFont reports whether the spec has the large digits of FX30 in memory.
+func (s Spec) hasLargeFont() bool {
+	switch s {
+	case Super10, Super, SuperModern, Xo, MegaChip:
+		return true
+	}
+	return false
+}
+
+// memorySize returns the number of bytes of memory of the spec.
+func (s Spec) memorySize() int {
+	switch s {
+	case MegaChip:
+		return megaMemorySize
+	case Xo:
+		// xo-chip has 64K of memory, addressed by the 16 bits of F000 NNNN.
+		return 0x10000
+	default:
+		return 4096
+	}
 }
 
-// ParseChip8Spec is a function that parses the string passed as a cli argument by the user to one of the
-// as a Spec for use in emulator.
-func ParseChip8Spec(specArgument *string) Spec {
-	spec, ok := Specs[*specArgument]
+// ParseChip8Spec is a function that parses the name of a spec passed by the user to a Spec for use in emulator.
+func ParseChip8Spec(name string) (Spec, error) {
+	spec, ok := Specs[name]
 	if !ok {
-		fmt.Println("No spec specified, derfaulting to Original.")
-		return Original
+		return Original, fmt.Errorf("unknown spec %q, the specs are original, hires, chip48, super10, super, superc, xo, chip8x and megachip", name)
 	}
-	return spec
+	return spec, nil
 }
@@ -0,0 +1,112 @@
+package ch8
+
+const (
+	// vipCyclesPerFrame is the number of machine cycles the CDP1802 of the COSMAC VIP runs in a frame. It runs at
+	// 1.76064 MHz and a machine cycle takes 8 clock cycles.
+	vipCyclesPerFrame = 1760640 / 8 / FramesPerSecond
+
+	// vipInterruptCycles is the part of a frame taken by the display interrupt: the DMA of the 128 lines of the
+	// display, 8 bytes a line, and the interrupt routine that decrements the timers.
+	vipInterruptCycles = 128*8 + 46
+
+	// vipFetchCycles is the cost of fetching and decoding an instruction, which is paid by every instruction.
+	vipFetchCycles = 40
+)
+
+// emulateVIPFrame runs the instructions that fit in a frame of the COSMAC VIP. Every instruction costs the number
+// of machine cycles its routine takes in the VIP interpreter, and an instruction that does not fit in the frame
+// any more runs past its end, taking its cycles from the next frame. Drawing waits for the display interrupt, so
+// the cycles left in the frame are lost.
+func (ch8 *CPU) emulateVIPFrame() {
+	ch8.cycleBudget += vipCyclesPerFrame - vipInterruptCycles
+	for ch8.cycleBudget > 0 {
+		opcode := ch8.readOpcode()
+		programCounter := ch8.programCounter
+		vx := ch8.registers[(opcode&0x0F00)>>8]
+
+		waitForInterrupt := ch8.executeInstruction()
+		skipped := ch8.programCounter == programCounter+4
+		ch8.cycleBudget -= vipFetchCycles + vipInstructionCycles(opcode, vx, skipped)
+
+		if waitForInterrupt {
+			ch8.cycleBudget = min(ch8.cycleBudget, 0)
+			return
+		}
+	}
+}
+
+// vipInstructionCycles returns the approximate number of machine cycles the VIP interpreter takes to execute an
+// instruction, not counting its fetch. vx is the value of VX before the instruction and skipped reports whether
+// a skip instruction skipped.
+func vipInstructionCycles(opcode uint16, vx byte, skipped bool) int {
+	x := int(opcode&0x0F00) >> 8
+	n := int(opcode & 0x000F)
+
+	skip := func(cycles int) int {
+		if skipped {
+			return cycles + 4
+		}
+		return cycles
+	}
+
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch opcode {
+		case 0x00E0:
+			return 3078 // the routine clears the 256 bytes of the display one by one
+		case 0x00EE:
+			return 10
+		}
+		return 0 // machine code routines are not emulated
+	case 0x1000:
+		return 12
+	case 0x2000:
+		return 26
+	case 0x3000, 0x4000:
+		return skip(10)
+	case 0x5000, 0x9000:
+		return skip(14)
+	case 0x6000:
+		return 6
+	case 0x7000:
+		return 10
+	case 0x8000:
+		return 44
+	case 0xA000:
+		return 12
+	case 0xB000:
+		return 22
+	case 0xC000:
+		return 36
+	case 0xD000:
+		return vipDrawCycles(vx, n)
+	case 0xE000:
+		return skip(14)
+	}
+
+	switch opcode & 0x00FF {
+	case 0x07, 0x15, 0x18:
+		return 10
+	case 0x0A:
+		return 16 // the cost of checking the keypad once, FX0A runs again while it waits for a key
+	case 0x1E, 0x29:
+		return 16
+	case 0x33:
+		// the routine counts the digits by repeated subtraction.
+		return 80 + 16*(int(vx/100)+int(vx/10%10)+int(vx%10))
+	case 0x55, 0x65:
+		return 14 + 14*(x+1)
+	}
+	return 0
+}
+
+// vipDrawCycles returns the number of machine cycles the VIP interpreter takes to draw a sprite of n rows at the
+// horizontal position x. Sprites that are not aligned to a byte of the display are shifted into two bytes, which
+// costs more for every row.
+func vipDrawCycles(x byte, n int) int {
+	cycles := 26 + 46*n
+	if x%8 != 0 {
+		cycles += 20 * n
+	}
+	return cycles
+}
@@ -0,0 +1,69 @@
+package ch8
+
+import "testing"
+
+// newVIPTimingCPU creates an original cpu with VIPTiming that runs the program followed by 6000 over and over.
+func newVIPTimingCPU(program ...uint16) *CPU {
+	for len(program) < 200 {
+		program = append(program, 0x6000)
+	}
+	cpu := newTestCPU(Original, program...)
+	cpu.VIPTiming = true
+	return cpu
+}
+
+// instructionsPerFrame runs the frames and returns the number of instructions run on each of them. The program
+// must not jump.
+func instructionsPerFrame(cpu *CPU, frames int) []int {
+	counts := make([]int, frames)
+	for i := range counts {
+		start := cpu.programCounter
+		cpu.Tick()
+		counts[i] = int(cpu.programCounter-start) / 2
+	}
+	return counts
+}
+
+func TestVIPTimingCarriesCyclesOver(t *testing.T) {
+	// 6000 takes 46 cycles and a frame has 2598 cycles for the interpreter: the 57th instruction runs 24 cycles past
+	// the end of the first frame, which leaves room for 56 instructions in the second.
+	counts := instructionsPerFrame(newVIPTimingCPU(), 2)
+	if counts[0] != 57 || counts[1] != 56 {
+		t.Fatalf("ran %v instructions, want [57 56]", counts)
+	}
+}
+
+func TestVIPTimingUnalignedSprites(t *testing.T) {
+	tests := []struct {
+		name string
+		x    uint16
+		want int
+	}{
+		// 60NN sets the x of the sprites drawn by D015, which takes 296 cycles at a multiple of 8 and 396 elsewhere.
+		{"aligned", 8, 10},
+		{"unaligned", 3, 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			program := []uint16{0x6000 | test.x}
+			for i := 0; i < 20; i++ {
+				program = append(program, 0xD015)
+			}
+			cpu := newVIPTimingCPU(program...)
+			cpu.Quirks.VBlank = false
+
+			if counts := instructionsPerFrame(cpu, 1); counts[0] != test.want {
+				t.Fatalf("ran %d instructions, want %d", counts[0], test.want)
+			}
+		})
+	}
+}
+
+func TestVIPTimingDrawWaitsForInterrupt(t *testing.T) {
+	// the cycles left after the draw are lost, the next frame starts with a full budget.
+	counts := instructionsPerFrame(newVIPTimingCPU(0x6000, 0xD015), 2)
+	if counts[0] != 2 || counts[1] != 57 {
+		t.Fatalf("ran %v instructions, want [2 57]", counts)
+	}
+}
@@ -0,0 +1,212 @@
+package ch8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	wavSampleRate    = 44100
+	wavBitsPerSample = 16
+	wavChannels      = 1
+
+	// samplesPerFrame is the number of samples that make up 1/60 of a second of audio.
+	samplesPerFrame = wavSampleRate / FramesPerSecond
+
+	toneFrequency = 440
+	toneAmplitude = 8000
+
+	// sampleAmplitude is the amplitude of the loudest sample of a MegaChip sound.
+	sampleAmplitude = toneAmplitude
+)
+
+// AudioRecorder is a Beep that records the sound of the chip-8 while forwarding the calls to another Beep.
+// Tick calls either Play or Pause exactly once per frame, so the recording is in sync with the emulated
+// frames rather than the wall clock: every call appends 1/60 of a second of audio.
+// It is also a SampleBeep, the samples of MegaChip are mixed with the tone.
+type AudioRecorder struct {
+	beep      Beep
+	samples   []int16
+	phase     int
+	recording bool
+
+	// sound is the MegaChip sample that is playing, or nil. soundPosition is the position in it, which moves by
+	// soundStep samples for every recorded sample.
+	sound         []byte
+	soundLoop     bool
+	soundPosition float64
+	soundStep     float64
+}
+
+// NewAudioRecorder creates an AudioRecorder that forwards the calls to beep. beep can be nil.
+func NewAudioRecorder(beep Beep) *AudioRecorder {
+	return &AudioRecorder{beep: beep}
+}
+
+// Play records one frame of the beep tone.
+func (r *AudioRecorder) Play() {
+	if r.beep != nil {
+		r.beep.Play()
+	}
+	r.recordFrame(true)
+}
+
+// Pause records one frame of silence.
+func (r *AudioRecorder) Pause() {
+	if r.beep != nil {
+		r.beep.Pause()
+	}
+	r.recordFrame(false)
+}
+
+// PlaySample starts mixing a MegaChip sample into the recording.
+func (r *AudioRecorder) PlaySample(samples []byte, rate int, loop bool) {
+	if beep, ok := r.beep.(SampleBeep); ok {
+		beep.PlaySample(samples, rate, loop)
+	}
+	r.sound, r.soundLoop = samples, loop
+	r.soundPosition, r.soundStep = 0, float64(rate)/wavSampleRate
+	if len(samples) == 0 || rate == 0 {
+		r.sound = nil
+	}
+}
+
+// StopSample stops the MegaChip sample.
+func (r *AudioRecorder) StopSample() {
+	if beep, ok := r.beep.(SampleBeep); ok {
+		beep.StopSample()
+	}
+	r.sound = nil
+}
+
+// Start discards any previously recorded audio and starts recording.
+func (r *AudioRecorder) Start() {
+	r.samples = r.samples[:0]
+	r.phase = 0
+	r.recording = true
+}
+
+// Stop stops recording. The recorded audio is kept until the next call to Start.
+func (r *AudioRecorder) Stop() {
+	r.recording = false
+}
+
+// Recording reports whether the recorder is currently recording.
+func (r *AudioRecorder) Recording() bool {
+	return r.recording
+}
+
+func (r *AudioRecorder) recordFrame(soundOn bool) {
+	if !r.recording {
+		// the sample keeps playing while nothing is recorded.
+		for i := 0; i < samplesPerFrame; i++ {
+			r.nextSample()
+		}
+		return
+	}
+
+	halfPeriod := wavSampleRate / toneFrequency / 2
+	for i := 0; i < samplesPerFrame; i++ {
+		var sample int
+		if soundOn {
+			// square wave, the phase is kept between frames so that the tone does not click.
+			if (r.phase/halfPeriod)%2 == 0 {
+				sample = toneAmplitude
+			} else {
+				sample = -toneAmplitude
+			}
+			r.phase++
+		}
+		sample += r.nextSample()
+		r.samples = append(r.samples, int16(max(math.MinInt16, min(math.MaxInt16, sample))))
+	}
+
+	if !soundOn {
+		r.phase = 0
+	}
+}
+
+// nextSample returns the next recorded sample of the MegaChip sample that is playing, or 0 if none is playing.
+func (r *AudioRecorder) nextSample() int {
+	if r.sound == nil {
+		return 0
+	}
+
+	sample := (int(r.sound[int(r.soundPosition)]) - 0x80) * sampleAmplitude / 0x80
+	r.soundPosition += r.soundStep
+	if int(r.soundPosition) >= len(r.sound) {
+		if r.soundLoop {
+			r.soundPosition = 0
+		} else {
+			r.sound = nil
+		}
+	}
+	return sample
+}
+
+// WriteWAV writes the recorded audio to w as a 16 bit mono PCM WAV file.
+func (r *AudioRecorder) WriteWAV(w io.Writer) error {
+	if err := writeWAVHeader(w, wavSampleRate, wavBitsPerSample, len(r.samples)*wavBitsPerSample/8); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.LittleEndian, r.samples)
+}
+
+// SampleWAV wraps the 8-bit unsigned samples of a MegaChip sound in a mono PCM WAV file played at the rate.
+func SampleWAV(samples []byte, rate int) []byte {
+	var file bytes.Buffer
+	// writing to a bytes.Buffer does not fail.
+	_ = writeWAVHeader(&file, rate, 8, len(samples))
+	file.Write(samples)
+	return file.Bytes()
+}
+
+// writeWAVHeader writes the header of a mono PCM WAV file with dataSize bytes of samples.
+func writeWAVHeader(w io.Writer, rate, bitsPerSample, dataSize int) error {
+	blockAlign := uint16(wavChannels * bitsPerSample / 8)
+
+	header := []any{
+		[4]byte{'R', 'I', 'F', 'F'},
+		uint32(36 + dataSize), // size of the rest of the file
+		[4]byte{'W', 'A', 'V', 'E'},
+
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16), // size of the fmt chunk
+		uint16(1),  // PCM
+		uint16(wavChannels),
+		uint32(rate),
+		uint32(rate) * uint32(blockAlign), // byte rate
+		blockAlign,
+		uint16(bitsPerSample),
+
+		[4]byte{'d', 'a', 't', 'a'},
+		uint32(dataSize),
+	}
+
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveWAV writes the recorded audio to a WAV file in the provided path.
+func (r *AudioRecorder) SaveWAV(path string) error {
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	if err := r.WriteWAV(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,45 @@
+package ch8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestSampleWAV(t *testing.T) {
+	samples := []byte{0x00, 0x80, 0xFF}
+	file := SampleWAV(samples, 8000)
+
+	if len(file) != 44+len(samples) || string(file[:4]) != "RIFF" || string(file[8:16]) != "WAVEfmt " ||
+		string(file[36:40]) != "data" {
+		t.Fatalf("SampleWAV wrote % X, which is not a WAV file", file)
+	}
+
+	fields := []struct {
+		name   string
+		offset int
+		value  uint32
+		size   int
+	}{
+		{"file size", 4, 36 + 3, 4},
+		{"channels", 22, 1, 2},
+		{"sample rate", 24, 8000, 4},
+		{"byte rate", 28, 8000, 4},
+		{"block align", 32, 1, 2},
+		{"bits per sample", 34, 8, 2},
+		{"data size", 40, 3, 4},
+	}
+	for _, field := range fields {
+		value := uint32(binary.LittleEndian.Uint16(file[field.offset:]))
+		if field.size == 4 {
+			value = binary.LittleEndian.Uint32(file[field.offset:])
+		}
+		if value != field.value {
+			t.Errorf("%s = %d, want %d", field.name, value, field.value)
+		}
+	}
+
+	if !bytes.Equal(file[44:], samples) {
+		t.Fatalf("samples = % X, want % X", file[44:], samples)
+	}
+}
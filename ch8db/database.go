@@ -0,0 +1,143 @@
+package ch8db
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/efeckgz/GoCh8/ch8"
+)
+
+// platform is a chip-8 platform of the database that the emulator supports.
+type platform struct {
+	spec   ch8.Spec
+	quirks ch8.Quirks
+}
+
+// platforms maps the ids of the platforms in the database to the specs and quirks used to emulate them.
+var platforms = map[string]platform{
+	"originalChip8": {ch8.Original, ch8.Original.DefaultQuirks()},
+	"hybridVIP":     {ch8.Original, ch8.Original.DefaultQuirks()},
+	"modernChip8":   {ch8.Original, ch8.Quirks{}},
+	"chip8x":        {ch8.Chip8X, ch8.Chip8X.DefaultQuirks()},
+	"megachip8":     {ch8.MegaChip, ch8.MegaChip.DefaultQuirks()},
+	"chip48":        {ch8.Chip48, ch8.Chip48.DefaultQuirks()},
+	"superchip1":    {ch8.Super10, ch8.Super10.DefaultQuirks()},
+	"superchip":     {ch8.Super, ch8.Super.DefaultQuirks()},
+	"xochip":        {ch8.Xo, ch8.Xo.DefaultQuirks()},
+}
+
+// Database is a rom database in the format of the chip-8 database (https://github.com/chip-8/chip-8-database).
+// Only the files sha1-hashes.json and programs.json are read, the platforms are built into the emulator.
+type Database struct {
+	hashes   map[string]int
+	programs []program
+}
+
+type program struct {
+	Title string         `json:"title"`
+	ROMs  map[string]rom `json:"roms"`
+}
+
+type rom struct {
+	Platforms       []string                   `json:"platforms"`
+	QuirkyPlatforms map[string]json.RawMessage `json:"quirkyPlatforms"`
+	Tickrate        int                        `json:"tickrate"`
+	Colors          struct {
+		Pixels []string `json:"pixels"`
+	} `json:"colors"`
+	Keys map[string]int `json:"keys"`
+}
+
+// Entry is the configuration of a rom found in the database.
+type Entry struct {
+	Title    string
+	Platform string
+	Spec     ch8.Spec
+	Quirks   ch8.Quirks
+
+	// Tickrate is the number of instructions to run per frame. It is 0 if the database does not specify it.
+	Tickrate float64
+
+	// Colors is the colors of the pixels written as #rrggbb, starting with the background.
+	Colors []string
+
+	// Keys maps the names of buttons such as "up", "left" or "a" to the keypad keys the rom uses for them.
+	Keys map[string]int
+}
+
+// DefaultPath returns the path of the database directory in the user's config directory.
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "GoCh8", "chip-8-database"), nil
+}
+
+// Load reads the database from the directory in the provided path.
+func Load(dir string) (*Database, error) {
+	db := &Database{}
+	if err := readJSON(filepath.Join(dir, "sha1-hashes.json"), &db.hashes); err != nil {
+		return nil, err
+	}
+	if err := readJSON(filepath.Join(dir, "programs.json"), &db.programs); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// Lookup finds the rom with the given SHA-1 hash. The first platform of the rom that the emulator supports is used.
+// ok is false if the rom is not in the database or none of its platforms are supported.
+func (db *Database) Lookup(hash string) (entry Entry, ok bool) {
+	index, found := db.hashes[hash]
+	if !found || index < 0 || index >= len(db.programs) {
+		return entry, false
+	}
+
+	program := db.programs[index]
+	rom, found := program.ROMs[hash]
+	if !found {
+		return entry, false
+	}
+
+	for _, id := range rom.Platforms {
+		platform, supported := platforms[id]
+		if !supported {
+			continue
+		}
+
+		entry = Entry{
+			Title:    program.Title,
+			Platform: id,
+			Spec:     platform.spec,
+			Quirks:   platform.quirks,
+			Tickrate: float64(rom.Tickrate),
+			Colors:   rom.Colors.Pixels,
+			Keys:     rom.Keys,
+		}
+
+		// quirky platforms only list the quirks that differ from the platform.
+		if quirks, found := rom.QuirkyPlatforms[id]; found {
+			if err := json.Unmarshal(quirks, &entry.Quirks); err != nil {
+				return entry, false
+			}
+		}
+		return entry, true
+	}
+
+	return entry, false
+}
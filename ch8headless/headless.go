@@ -0,0 +1,70 @@
+package ch8headless
+
+import (
+	"image/color"
+	"log"
+
+	"github.com/efeckgz/GoCh8/ch8"
+)
+
+// gifWidth is the width of the GIF recordings in pixels.
+const gifWidth = 640
+
+// RunHeadless runs the emulator for the given number of frames without opening a window or an audio device.
+// Frames are emulated as fast as possible. If wavPath is not empty, the sound of the run is saved there.
+// If gifPath is not empty, the display of the run is saved there as an animated GIF drawn with the bg and fg colors.
+// If playback is not nil, the keypad is driven by the movie, the settings of the movie are used instead of settings
+// and the run lasts as long as the movie.
+func RunHeadless(settings ch8.Settings, romPath string, frames int, wavPath, gifPath string, bg, fg color.Color, playback *ch8.Movie) {
+	if playback != nil {
+		settings = playback.Settings
+		frames = len(playback.Frames)
+	}
+
+	recorder := ch8.NewAudioRecorder(nil)
+	gifRecorder := ch8.NewGIFRecorder(gifWidth, bg, fg)
+	cpu := settings.NewCPU(recorder)
+
+	err := cpu.LoadProgram(romPath)
+	if err != nil {
+		log.Fatalf("Error loading program: %v\n", err)
+	}
+
+	if playback != nil {
+		if err := playback.Check(&cpu); err != nil {
+			log.Fatalf("Can not play the movie back: %v", err)
+		}
+	}
+
+	if wavPath != "" {
+		recorder.Start()
+	}
+
+	if gifPath != "" {
+		gifRecorder.Start()
+	}
+
+	for frame := 0; frame < frames; frame++ {
+		if playback != nil {
+			playback.PlayFrame(&cpu, frame)
+		}
+
+		cpu.Tick()
+		gifRecorder.RecordFrame(&cpu)
+		cpu.DisplayUpdated = false
+	}
+
+	if wavPath != "" {
+		recorder.Stop()
+		if err := recorder.SaveWAV(wavPath); err != nil {
+			log.Fatalf("Could not save the audio recording: %v", err)
+		}
+	}
+
+	if gifPath != "" {
+		gifRecorder.Stop()
+		if err := gifRecorder.SaveGIF(gifPath); err != nil {
+			log.Fatalf("Could not save the GIF recording: %v", err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ch8sdl
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// fastForwardFrames is the number of frames emulated in every frame while fast forwarding.
+	fastForwardFrames = 4
+
+	// slowMotionFrames is the number of frames every emulated frame is shown for in slow motion.
+	slowMotionFrames = 4
+
+	// tickrateStep is the factor the speed hotkeys change the tickrate by.
+	tickrateStep = 1.25
+)
+
+// control is the state of the emulation that is controlled by the hotkeys.
+type control struct {
+	paused bool
+
+	// advance is raised to emulate a single frame while paused.
+	advance bool
+
+	fastForward bool
+	slowMotion  bool
+
+	// slowMotionFrame counts the frames an emulated frame has been shown for in slow motion.
+	slowMotionFrame int
+}
+
+// framesToRun returns the number of frames to emulate in the current frame.
+func (c *control) framesToRun() int {
+	switch {
+	case c.paused && c.advance:
+		c.advance = false
+		return 1
+	case c.paused:
+		return 0
+	case c.fastForward:
+		return fastForwardFrames
+	case c.slowMotion:
+		c.slowMotionFrame = (c.slowMotionFrame + 1) % slowMotionFrames
+		if c.slowMotionFrame == 0 {
+			return 1
+		}
+		return 0
+	}
+	return 1
+}
+
+// indicator returns the indicator drawn on the display for the state.
+func (c *control) indicator() indicator {
+	switch {
+	case c.paused:
+		return pausedIndicator
+	case c.fastForward:
+		return fastForwardIndicator
+	case c.slowMotion:
+		return slowMotionIndicator
+	}
+	return noIndicator
+}
+
+// status describes the state and the tickrate for the title of the window. It is empty when the emulation runs
+// normally at the tickrate it was started with.
+func (c *control) status(tickrate, startTickrate float64) string {
+	var parts []string
+	switch {
+	case c.paused:
+		parts = append(parts, "paused")
+	case c.fastForward:
+		parts = append(parts, "fast forward")
+	case c.slowMotion:
+		parts = append(parts, "slow motion")
+	}
+	if tickrate != startTickrate {
+		parts = append(parts, fmt.Sprintf("%.4g instructions per frame", tickrate))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// indicator represents the icons drawn on the display to show the state of the emulation.
+type indicator byte
+
+const (
+	noIndicator indicator = iota
+	pausedIndicator
+	fastForwardIndicator
+	slowMotionIndicator
+)
+
+// rects returns the rectangles of the icon of the indicator on a display of the provided height, as x, y, width
+// and height. The icon is drawn in the top left corner and scales with the display.
+func (i indicator) rects(height int) [][4]int {
+	unit := max(1, height/32)
+	var rects [][4]int
+
+	// triangle adds a triangle pointing right, made of columns that get shorter.
+	triangle := func(x int) {
+		for column := 0; column < 3; column++ {
+			rects = append(rects, [4]int{(x + column) * unit, (1 + column) * unit, unit, (5 - 2*column) * unit})
+		}
+	}
+
+	switch i {
+	case pausedIndicator:
+		rects = append(rects, [4]int{unit, unit, unit, 5 * unit}, [4]int{3 * unit, unit, unit, 5 * unit})
+	case fastForwardIndicator:
+		triangle(1)
+		triangle(4)
+	case slowMotionIndicator:
+		rects = append(rects, [4]int{unit, unit, unit, 5 * unit})
+		triangle(3)
+	}
+	return rects
+}
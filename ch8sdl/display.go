@@ -0,0 +1,200 @@
+package ch8sdl
+
+import (
+	"fmt"
+	"image/color"
+	"unsafe"
+
+	"github.com/efeckgz/GoCh8/ch8"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// display draws the frames of the emulator to the window. The pixel levels of a frame are converted to pixels that
+// are uploaded to a streaming texture, which the renderer scales to the window with a single copy.
+type display struct {
+	renderer *sdl.Renderer
+
+	// textures holds a texture for every resolution of the display.
+	textures map[[2]int]*sdl.Texture
+
+	// frame is the last frame taken from the emulator.
+	frame frame
+
+	// pixels is the RGBA pixels of the last frame, 4 bytes per pixel.
+	pixels []byte
+
+	// effects is the effects the display is drawn with, and selectedEffects is the effects that are switched on
+	// by toggleEffects.
+	effects, selectedEffects Effects
+	effectRenderer           *effectRenderer
+	effectTexture            *sdl.Texture
+}
+
+// newDisplay creates a display that draws with the provided renderer and options. The effects are drawn at the
+// size of the window the display is created for.
+func newDisplay(renderer *sdl.Renderer, options Options) (*display, error) {
+	d := &display{
+		renderer:        renderer,
+		textures:        map[[2]int]*sdl.Texture{},
+		pixels:          make([]byte, 256*192*4),
+		effects:         options.Effects,
+		selectedEffects: options.Effects,
+		effectRenderer:  newEffectRenderer(options.windowWidth(), options.windowHeight()),
+	}
+	d.frame.renderingMode, d.frame.width, d.frame.height = ch8.LoresRendering, 64, 32
+	if d.selectedEffects == NoEffects {
+		d.selectedEffects = crtEffects
+	}
+
+	// lores, hi-res CHIP-8, hires and MegaChip mode.
+	resolutions := [][2]int{{64, 32}, {64, 64}, {128, 64}, {256, 192}}
+	for _, resolution := range resolutions {
+		texture, err := renderer.CreateTexture(uint32(sdl.PIXELFORMAT_RGBA32), sdl.TEXTUREACCESS_STREAMING, int32(resolution[0]), int32(resolution[1]))
+		if err != nil {
+			d.destroy()
+			return nil, fmt.Errorf("could not create the display texture: %v", err)
+		}
+		d.textures[resolution] = texture
+	}
+
+	texture, err := renderer.CreateTexture(uint32(sdl.PIXELFORMAT_RGBA32), sdl.TEXTUREACCESS_STREAMING, int32(options.windowWidth()), int32(options.windowHeight()))
+	if err != nil {
+		d.destroy()
+		return nil, fmt.Errorf("could not create the effect texture: %v", err)
+	}
+	d.effectTexture = texture
+
+	return d, nil
+}
+
+// toggleEffects switches the selected effects on or off and returns the effects that are used from now on.
+func (d *display) toggleEffects() Effects {
+	if d.effects == NoEffects {
+		d.effects = d.selectedEffects
+	} else {
+		d.effects = NoEffects
+	}
+	return d.effects
+}
+
+// pixelColors returns the background and foreground colors of the chip-8 pixel x, y.
+type pixelColors func(x, y int) (bg, fg color.RGBA)
+
+// draw draws the visible part of the last frame to the window using the colors of the palette, or the colors of
+// the color overlay of the frame if it has one.
+func (d *display) draw(palette Palette) error {
+	if d.frame.renderingMode == ch8.MegaRendering {
+		return d.drawMega(palette)
+	}
+
+	width, height := d.frame.width, d.frame.height
+
+	colors := func(int, int) (color.RGBA, color.RGBA) {
+		return palette.Background, palette.Color(1)
+	}
+	if d.frame.colored {
+		colors = func(x, y int) (color.RGBA, color.RGBA) {
+			return d.frame.overlay.Background, d.frame.overlay.At(x, y)
+		}
+	}
+	// the indicator keeps the foreground color of the palette, the overlay may color the corner of the display black.
+	bg, _ := colors(0, 0)
+	fg := palette.Color(1)
+
+	if d.effects != NoEffects {
+		d.effectRenderer.render(&d.frame.levels, width, height, colors, d.effects)
+		return d.present(d.effectTexture, d.effectRenderer.pixels, d.effectRenderer.width, d.effectRenderer.height, bg, fg)
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixelBg, pixelFg := colors(x, y)
+			c := mixColors(pixelBg, pixelFg, d.frame.levels[y][x])
+			i := (y*width + x) * 4
+			d.pixels[i], d.pixels[i+1], d.pixels[i+2], d.pixels[i+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	return d.present(d.textures[[2]int{width, height}], d.pixels, width, height, bg, fg)
+}
+
+// drawMega draws the last frame of MegaChip mode, which has its own colors. The colors are drawn over black without
+// effects.
+func (d *display) drawMega(palette Palette) error {
+	for y, row := range d.frame.mega {
+		for x, c := range row {
+			i := (y*len(row) + x) * 4
+			d.pixels[i] = uint8(uint16(c.R) * uint16(c.A) / 0xFF)
+			d.pixels[i+1] = uint8(uint16(c.G) * uint16(c.A) / 0xFF)
+			d.pixels[i+2] = uint8(uint16(c.B) * uint16(c.A) / 0xFF)
+			d.pixels[i+3] = 0xFF
+		}
+	}
+	black := color.RGBA{A: 0xFF}
+	return d.present(d.textures[[2]int{256, 192}], d.pixels, 256, 192, black, palette.Color(1))
+}
+
+// present uploads the pixels of an image of the provided size to the texture and shows it in the window. Images
+// without effects are scaled by whole numbers to keep the pixels sharp. Images with effects are already drawn at
+// the size of the window, so they are scaled to fill it. The border is cleared with bg and the indicator is drawn
+// with fg.
+func (d *display) present(texture *sdl.Texture, pixels []byte, width, height int, bg, fg color.RGBA) error {
+	if err := texture.Update(nil, unsafe.Pointer(&pixels[0]), width*4); err != nil {
+		return fmt.Errorf("could not update the display texture: %v", err)
+	}
+
+	// the 64x64 display of hi-res CHIP-8 fills the same screen as the other modes of the COSMAC VIP, with pixels
+	// twice as wide as they are high.
+	logicalWidth := width
+	if width == height {
+		logicalWidth = 2 * width
+	}
+
+	if err := d.renderer.SetIntegerScale(texture != d.effectTexture); err != nil {
+		return fmt.Errorf("could not set the scaling of the renderer: %v", err)
+	}
+	if err := d.renderer.SetLogicalSize(int32(logicalWidth), int32(height)); err != nil {
+		return fmt.Errorf("could not set the logical size of the renderer: %v", err)
+	}
+
+	// the border around the display is cleared with the background color.
+	if err := d.renderer.SetDrawColor(bg.R, bg.G, bg.B, bg.A); err != nil {
+		return fmt.Errorf("could not set the drawing color: %v", err)
+	}
+	if err := d.renderer.Clear(); err != nil {
+		return fmt.Errorf("could not clear the window: %v", err)
+	}
+	if err := d.renderer.Copy(texture, nil, nil); err != nil {
+		return fmt.Errorf("could not copy the display texture: %v", err)
+	}
+
+	if err := d.renderer.SetDrawColor(fg.R, fg.G, fg.B, fg.A); err != nil {
+		return fmt.Errorf("could not set the drawing color: %v", err)
+	}
+	for _, rect := range d.frame.indicator.rects(height) {
+		err := d.renderer.FillRect(&sdl.Rect{X: int32(rect[0]), Y: int32(rect[1]), W: int32(rect[2]), H: int32(rect[3])})
+		if err != nil {
+			return fmt.Errorf("could not draw the indicator: %v", err)
+		}
+	}
+
+	d.renderer.Present()
+	return nil
+}
+
+// mixColors returns the color between a and b, where level 0 is a and level 1 is b.
+func mixColors(a, b color.RGBA, level float64) color.RGBA {
+	channel := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*level + 0.5)
+	}
+	return color.RGBA{R: channel(a.R, b.R), G: channel(a.G, b.G), B: channel(a.B, b.B), A: channel(a.A, b.A)}
+}
+
+// destroy frees the textures of the display.
+func (d *display) destroy() {
+	for _, texture := range d.textures {
+		texture.Destroy()
+	}
+	if d.effectTexture != nil {
+		d.effectTexture.Destroy()
+	}
+}
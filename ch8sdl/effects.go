@@ -0,0 +1,192 @@
+package ch8sdl
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// Effects is a set of post-processing effects that give the display a retro look. The effects are drawn at the
+// size of the window the emulator is started with rather than at the resolution of the chip-8.
+type Effects byte
+
+const (
+	// ScanlinesEffect darkens the edges of every row of pixels, like the scanlines of a CRT.
+	ScanlinesEffect Effects = 1 << iota
+
+	// GridEffect leaves a gap between the pixels, like the LCD of an HP48.
+	GridEffect
+
+	// BloomEffect makes the lit pixels glow into their neighbours.
+	BloomEffect
+
+	// CurvatureEffect bends the display like the glass of a CRT.
+	CurvatureEffect
+
+	// NoEffects draws the display without effects.
+	NoEffects Effects = 0
+)
+
+// crtEffects is used by the effects hotkey when no effects are selected.
+const crtEffects = ScanlinesEffect | BloomEffect | CurvatureEffect
+
+var effectNames = map[string]Effects{
+	"none":      NoEffects,
+	"scanlines": ScanlinesEffect,
+	"grid":      GridEffect,
+	"bloom":     BloomEffect,
+	"curvature": CurvatureEffect,
+	"crt":       crtEffects,
+	"lcd":       GridEffect,
+}
+
+const (
+	// scanlineDepth is how much the edges of a row are darkened by the ScanlinesEffect.
+	scanlineDepth = 0.45
+
+	// gridGap is the part of a pixel that is left as a gap by the GridEffect, and gridLevel is how much of the
+	// pixel shows through the gap.
+	gridGap   = 0.15
+	gridLevel = 0.2
+
+	// bloomStrength is how much of the glow is added to the pixels by the BloomEffect.
+	bloomStrength = 0.5
+
+	// curvature is how much the CurvatureEffect bends the corners of the display.
+	curvature = 0.08
+)
+
+// ParseEffects takes a comma separated list of effect names passed by the user, such as "scanlines,bloom", and
+// returns the set of effects. crt selects scanlines, bloom and curvature and lcd selects the grid.
+func ParseEffects(names string) (Effects, error) {
+	effects := NoEffects
+	for _, name := range strings.Split(names, ",") {
+		effect, ok := effectNames[strings.TrimSpace(name)]
+		if !ok {
+			return NoEffects, fmt.Errorf("unknown effect %q, the effects are scanlines, grid, bloom, curvature, crt, lcd and none", name)
+		}
+		effects |= effect
+	}
+	return effects, nil
+}
+
+// effectRenderer draws the pixel levels of the display with effects.
+type effectRenderer struct {
+	width, height int
+
+	// pixels is the RGBA pixels of the last frame, 4 bytes per pixel.
+	pixels []byte
+
+	// glow is the blurred pixel levels used by the BloomEffect.
+	glow [64][128]float64
+}
+
+// newEffectRenderer creates an effectRenderer that draws images of the provided size.
+func newEffectRenderer(width, height int) *effectRenderer {
+	return &effectRenderer{width: width, height: height, pixels: make([]byte, width*height*4)}
+}
+
+// render draws the levels of the visible part of the display with the effects. colors returns the background and
+// foreground colors of a chip-8 pixel.
+func (r *effectRenderer) render(levels *[64][128]float64, resolutionX, resolutionY int, colors pixelColors, effects Effects) {
+	if effects&BloomEffect != 0 {
+		r.blur(levels, resolutionX, resolutionY)
+	}
+
+	for oy := 0; oy < r.height; oy++ {
+		for ox := 0; ox < r.width; ox++ {
+			// u and v are the position of the pixel on the display between 0 and 1.
+			u, v := (float64(ox)+0.5)/float64(r.width), (float64(oy)+0.5)/float64(r.height)
+			c := color.RGBA{A: colorAlpha}
+			if effects&CurvatureEffect != 0 {
+				u, v = curve(u, v)
+			}
+
+			if u >= 0 && u < 1 && v >= 0 && v < 1 {
+				c = r.shade(levels, u*float64(resolutionX), v*float64(resolutionY), colors, effects)
+			}
+
+			i := (oy*r.width + ox) * 4
+			r.pixels[i], r.pixels[i+1], r.pixels[i+2], r.pixels[i+3] = c.R, c.G, c.B, c.A
+		}
+	}
+}
+
+// shade returns the color of the point x, y of the display, measured in chip-8 pixels.
+func (r *effectRenderer) shade(levels *[64][128]float64, x, y float64, colors pixelColors, effects Effects) color.RGBA {
+	// fracX and fracY are the position of the point inside its chip-8 pixel.
+	fracX, fracY := x-math.Floor(x), y-math.Floor(y)
+	level := levels[int(y)][int(x)]
+	bg, fg := colors(int(x), int(y))
+
+	if effects&GridEffect != 0 && (fracX > 1-gridGap || fracY > 1-gridGap) {
+		level *= gridLevel
+	}
+
+	c := mixColors(bg, fg, level)
+
+	if effects&BloomEffect != 0 {
+		glow := bloomStrength * r.glowAt(x, y)
+		c = color.RGBA{R: addChannel(c.R, fg.R, glow), G: addChannel(c.G, fg.G, glow), B: addChannel(c.B, fg.B, glow), A: c.A}
+	}
+
+	if effects&ScanlinesEffect != 0 {
+		brightness := 1 - scanlineDepth*(1-math.Cos(2*math.Pi*(fracY-0.5)))/2
+		c = mixColors(color.RGBA{A: c.A}, c, brightness)
+	}
+
+	return c
+}
+
+// blur fills the glow with the levels blurred over the neighbouring pixels.
+func (r *effectRenderer) blur(levels *[64][128]float64, resolutionX, resolutionY int) {
+	r.glow = [64][128]float64{}
+	weights := [3]float64{0.25, 0.5, 0.25}
+	for y := 0; y < resolutionY; y++ {
+		for x := 0; x < resolutionX; x++ {
+			sum := 0.0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= resolutionX || ny >= resolutionY {
+						continue
+					}
+					sum += weights[dx+1] * weights[dy+1] * levels[ny][nx]
+				}
+			}
+			r.glow[y][x] = sum
+		}
+	}
+}
+
+// glowAt returns the glow at the point x, y of the display, interpolated between the centers of the pixels.
+func (r *effectRenderer) glowAt(x, y float64) float64 {
+	x, y = x-0.5, y-0.5
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= len(r.glow[0]) || y >= len(r.glow) {
+			return 0
+		}
+		return r.glow[y][x]
+	}
+
+	top := at(x0, y0)*(1-fx) + at(x0+1, y0)*fx
+	bottom := at(x0, y0+1)*(1-fx) + at(x0+1, y0+1)*fx
+	return top*(1-fy) + bottom*fy
+}
+
+// curve maps a point of the window to the point of the display that is shown there by the CurvatureEffect.
+// Points outside the display are out of the range 0 to 1.
+func curve(u, v float64) (float64, float64) {
+	cu, cv := u*2-1, v*2-1
+	cu, cv = cu*(1+curvature*cv*cv), cv*(1+curvature*cu*cu)
+	return (cu + 1) / 2, (cv + 1) / 2
+}
+
+// addChannel adds amount of the color channel b to a, without going over the maximum.
+func addChannel(a, b uint8, amount float64) uint8 {
+	return uint8(math.Min(255, float64(a)+float64(b)*amount))
+}
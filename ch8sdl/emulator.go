@@ -0,0 +1,321 @@
+package ch8sdl
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/efeckgz/GoCh8/ch8"
+)
+
+const (
+	// frameDuration is the exact duration of a frame. ch8.FrameDelay is rounded to whole milliseconds.
+	frameDuration = time.Second / ch8.FramesPerSecond
+
+	// maxLag is how far the emulation can fall behind before the missed frames are dropped rather than caught
+	// up with.
+	maxLag = 10 * frameDuration
+)
+
+// keyEvent is a change of the state of a keypad key.
+type keyEvent struct {
+	key     byte
+	pressed bool
+}
+
+// command is a hotkey sent to the emulation goroutine. palette is the palette used by the render loop when the
+// hotkey was pressed.
+type command struct {
+	hotkey  Hotkey
+	pressed bool
+	palette Palette
+}
+
+// frame is a frame of the emulation handed over to the render loop.
+type frame struct {
+	levels        [64][128]float64
+	renderingMode ch8.RenderingMode
+	indicator     indicator
+
+	// width and height is the resolution of the display in the rendering mode of the frame.
+	width, height int
+
+	// overlay is the color overlay the frame is drawn with when colored is raised.
+	overlay ch8.ColorOverlay
+	colored bool
+
+	// mega is the display of MegaChip mode, which is drawn instead of the levels in MegaRendering.
+	mega ch8.MegaBuffer
+
+	// status describes the state of the emulation for the title of the window.
+	status string
+}
+
+// frameBuffer is a double buffer of frames. The emulation goroutine fills the back frame and swaps it with the
+// front frame, which the render loop copies.
+type frameBuffer struct {
+	mutex  sync.Mutex
+	frames [2]frame
+	front  int
+
+	// fresh is raised when the front frame has not been taken yet.
+	fresh bool
+}
+
+// back returns the frame the emulation goroutine fills. Only the emulation goroutine can use it.
+func (b *frameBuffer) back() *frame {
+	return &b.frames[1-b.front]
+}
+
+// swap hands the back frame over to the render loop.
+func (b *frameBuffer) swap() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.front = 1 - b.front
+	b.fresh = true
+}
+
+// take copies the front frame to f if it has not been taken yet. It reports whether f was written to.
+func (b *frameBuffer) take(f *frame) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.fresh {
+		return false
+	}
+	*f = b.frames[b.front]
+	b.fresh = false
+	return true
+}
+
+// emulator runs the cpu on its own goroutine. The cpu, the recorders and the movies are only used by that
+// goroutine while it runs. The render loop sends it the input through the keys and commands channels and receives
+// the frames through the frame buffer.
+type emulator struct {
+	settings ch8.Settings
+	romPath  string
+	options  Options
+
+	cpu         ch8.CPU
+	sound       *sound
+	recorder    *ch8.AudioRecorder
+	gifRecorder *ch8.GIFRecorder
+	levels      *pixelLevels
+	control     control
+
+	playback *ch8.Movie
+	movie    *ch8.Movie
+
+	// frame is the number of frames emulated since the start.
+	frame int
+
+	// startTickrate is the tickrate the session was started with.
+	startTickrate float64
+
+	keys     chan keyEvent
+	commands chan command
+	frames   frameBuffer
+
+	// changed is raised when the frame handed over to the render loop is out of date.
+	changed bool
+}
+
+// newCPU creates a cpu with the settings of the session and loads the rom into it.
+func (e *emulator) newCPU() (ch8.CPU, error) {
+	cpu := e.settings.NewCPU(e.recorder)
+	err := cpu.LoadProgram(e.romPath)
+	return cpu, err
+}
+
+// run emulates frames at 60 Hz until quit is closed. Frames are paced by the time accumulated since the start,
+// so that the time taken by a frame does not add up.
+func (e *emulator) run(quit <-chan struct{}) {
+	next := time.Now()
+	for {
+		select {
+		case <-quit:
+			return
+		default:
+		}
+
+		e.handleInput()
+		for frames := e.control.framesToRun(); frames > 0; frames-- {
+			e.runFrame()
+		}
+
+		if e.changed {
+			e.publish()
+			e.changed = false
+		}
+
+		next = next.Add(frameDuration)
+		wait := time.Until(next)
+		if wait < -maxLag {
+			next = time.Now()
+		}
+		time.Sleep(wait)
+	}
+}
+
+// handleInput applies the key events and the commands sent since the last frame.
+func (e *emulator) handleInput() {
+	for {
+		select {
+		case event := <-e.keys:
+			if event.pressed {
+				e.cpu.PressKey(event.key)
+			} else {
+				e.cpu.ReleaseKey(event.key)
+			}
+		case command := <-e.commands:
+			e.handleHotkey(command)
+		default:
+			return
+		}
+	}
+}
+
+// runFrame emulates a single frame. The pixel levels are updated on every frame when a flicker filter is used,
+// so that the filter sees every frame even when several frames are emulated before the window is drawn.
+func (e *emulator) runFrame() {
+	if e.playback != nil && !e.playback.PlayFrame(&e.cpu, e.frame) {
+		fmt.Println("Movie playback finished.")
+		e.playback = nil
+	}
+
+	if e.movie != nil {
+		e.movie.RecordFrame(&e.cpu)
+	}
+
+	e.cpu.Tick()
+	e.gifRecorder.RecordFrame(&e.cpu)
+	e.frame++
+	if e.cpu.DisplayUpdated || e.levels.filter != NoFlickerFilter {
+		e.levels.update(e.cpu.DisplayBuffer, e.cpu.RenderingMode)
+		e.cpu.DisplayUpdated = false
+		e.changed = true
+	}
+}
+
+// publish hands the current frame over to the render loop.
+func (e *emulator) publish() {
+	f := e.frames.back()
+	f.levels = e.levels.levels
+	f.renderingMode = e.cpu.RenderingMode
+	f.width, f.height = e.cpu.Resolution()
+	f.overlay, f.colored = e.cpu.ColorOverlay()
+	if mega := e.cpu.MegaDisplay(); mega != nil {
+		f.mega = *mega
+	}
+	f.indicator = e.control.indicator()
+	f.status = e.control.status(e.cpu.Tickrate, e.startTickrate)
+	e.frames.swap()
+}
+
+// handleHotkey performs the emulator action of a hotkey when it is pressed. Fast forward lasts as long as its key
+// is held, the other hotkeys only act when their key is pressed.
+func (e *emulator) handleHotkey(c command) {
+	e.options.Palette = c.palette
+	if c.hotkey == FastForwardHotkey {
+		e.control.fastForward = c.pressed
+		e.changed = true
+		return
+	}
+
+	if !c.pressed {
+		return
+	}
+
+	switch c.hotkey {
+	case ScreenshotHotkey:
+		saveScreenshot(&e.cpu, e.romPath, e.frame, e.options)
+	case CyclePaletteHotkey:
+		e.gifRecorder.SetColors(e.options.Palette.Background, e.options.Palette.Foreground)
+	case RecordAudioHotkey:
+		if e.recorder.Recording() {
+			saveAudioRecording(e.recorder, e.romPath, e.frame)
+		} else {
+			e.recorder.Start()
+			fmt.Println("Recording audio...")
+		}
+	case RecordGIFHotkey:
+		if e.gifRecorder.Recording() {
+			saveGIFRecording(e.gifRecorder, e.romPath, e.frame)
+		} else {
+			e.gifRecorder.Start()
+			fmt.Println("Recording GIF...")
+		}
+	case PauseHotkey:
+		e.control.paused = !e.control.paused
+		if e.control.paused {
+			e.sound.Pause()
+		}
+		e.sound.pauseSample(e.control.paused)
+	case FrameAdvanceHotkey:
+		e.control.paused, e.control.advance = true, true
+		e.sound.Pause()
+	case SlowMotionHotkey:
+		e.control.slowMotion = !e.control.slowMotion
+	case ResetHotkey:
+		e.reset()
+	case SpeedUpHotkey:
+		e.changeTickrate(tickrateStep)
+	case SpeedDownHotkey:
+		e.changeTickrate(1 / tickrateStep)
+	}
+	e.changed = true
+}
+
+// reset restarts the rom on a new cpu. Movies can not reproduce a reset, so it is not available while a movie
+// is recorded or played back.
+func (e *emulator) reset() {
+	if e.movie != nil || e.playback != nil {
+		fmt.Println("Reset is not available while a movie is recorded or played back.")
+		return
+	}
+
+	cpu, err := e.newCPU()
+	if err != nil {
+		log.Printf("Could not reset: %v", err)
+		return
+	}
+	e.sound.Pause()
+	e.sound.StopSample()
+	e.cpu = cpu
+	e.levels.update(e.cpu.DisplayBuffer, e.cpu.RenderingMode)
+	fmt.Println("Reset.")
+}
+
+// changeTickrate multiplies the tickrate by factor. The new tickrate is also used after a reset. Movies store a
+// single tickrate, so the tickrate can not be changed while a movie is recorded or played back.
+func (e *emulator) changeTickrate(factor float64) {
+	if e.movie != nil || e.playback != nil {
+		fmt.Println("The speed can not be changed while a movie is recorded or played back.")
+		return
+	}
+	if e.cpu.VIPTiming {
+		fmt.Println("The speed can not be changed with the VIP timing.")
+		return
+	}
+
+	e.cpu.Tickrate *= factor
+	e.settings.Tickrate = e.cpu.Tickrate
+}
+
+// finish saves the recordings that are still running and the movie. It must be called after run returns.
+func (e *emulator) finish(recordPath string) {
+	if e.recorder.Recording() {
+		saveAudioRecording(e.recorder, e.romPath, e.frame)
+	}
+
+	if e.gifRecorder.Recording() {
+		saveGIFRecording(e.gifRecorder, e.romPath, e.frame)
+	}
+
+	if e.movie != nil {
+		if err := e.movie.SaveMovie(recordPath); err != nil {
+			log.Fatalf("Could not save the movie: %v", err)
+		}
+		fmt.Printf("Movie saved to %s\n", recordPath)
+	}
+}
@@ -0,0 +1,88 @@
+package ch8sdl
+
+import (
+	"fmt"
+
+	"github.com/efeckgz/GoCh8/ch8"
+)
+
+// FlickerFilter represents the ways the display can hide the flicker of sprites that are erased and drawn again
+// every frame. The filters only change how the display is drawn, not the display buffer of the cpu.
+type FlickerFilter byte
+
+const (
+	// NoFlickerFilter draws the display buffer as it is.
+	NoFlickerFilter FlickerFilter = iota
+
+	// PhosphorFilter fades pixels out over a number of frames after they are erased, like the phosphor of a CRT.
+	PhosphorFilter
+
+	// BlendFilter draws the pixels that are set in the current or the previous frame.
+	BlendFilter
+)
+
+// DefaultPhosphorFrames is the default number of frames it takes the PhosphorFilter to fade a pixel out.
+const DefaultPhosphorFrames = 4
+
+var flickerFilters = map[string]FlickerFilter{
+	"none":     NoFlickerFilter,
+	"phosphor": PhosphorFilter,
+	"blend":    BlendFilter,
+}
+
+// ParseFlickerFilter takes the name of a flicker filter passed by the user and returns the appropriate filter.
+func ParseFlickerFilter(name string) (FlickerFilter, error) {
+	filter, ok := flickerFilters[name]
+	if !ok {
+		return NoFlickerFilter, fmt.Errorf("unknown flicker filter %q, the flicker filters are none, phosphor and blend", name)
+	}
+	return filter, nil
+}
+
+// pixelLevels holds how bright every pixel of the display is, between 0 for the background and 1 for the
+// foreground, after the flicker filter is applied.
+type pixelLevels struct {
+	filter FlickerFilter
+
+	// fade is how much the level of an erased pixel decreases every frame with the PhosphorFilter.
+	fade float64
+
+	levels        [64][128]float64
+	previous      [64][128]bool // the display buffer of the previous frame, used by the BlendFilter
+	renderingMode ch8.RenderingMode
+}
+
+// newPixelLevels creates the pixel levels for the filter. frames is only used by the PhosphorFilter.
+func newPixelLevels(filter FlickerFilter, frames int) *pixelLevels {
+	if frames <= 0 {
+		frames = DefaultPhosphorFrames
+	}
+	return &pixelLevels{filter: filter, fade: 1 / float64(frames)}
+}
+
+// update applies the filter to a frame of the display buffer. Filters other than NoFlickerFilter need to be
+// updated on every frame, even when the display buffer does not change.
+func (p *pixelLevels) update(displayBuffer [64][128]bool, renderingMode ch8.RenderingMode) {
+	// the pixels of the previous resolution do not fade into the new one.
+	if renderingMode != p.renderingMode {
+		p.levels = [64][128]float64{}
+		p.previous = [64][128]bool{}
+		p.renderingMode = renderingMode
+	}
+
+	for y, row := range displayBuffer {
+		for x, pixel := range row {
+			switch {
+			case pixel:
+				p.levels[y][x] = 1
+			case p.filter == PhosphorFilter:
+				p.levels[y][x] = max(0, p.levels[y][x]-p.fade)
+			case p.filter == BlendFilter && p.previous[y][x]:
+				p.levels[y][x] = 1
+			default:
+				p.levels[y][x] = 0
+			}
+		}
+	}
+	p.previous = displayBuffer
+}
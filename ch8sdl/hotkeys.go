@@ -0,0 +1,141 @@
+package ch8sdl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Hotkey is an action of the emulator that can be bound to a key.
+type Hotkey string
+
+const (
+	// RecordAudioHotkey starts and stops recording the audio to a WAV file.
+	RecordAudioHotkey Hotkey = "recordAudio"
+
+	// RecordGIFHotkey starts and stops recording the display to an animated GIF.
+	RecordGIFHotkey Hotkey = "recordGIF"
+
+	// ScreenshotHotkey saves a screenshot to a PNG file.
+	ScreenshotHotkey Hotkey = "screenshot"
+
+	// CyclePaletteHotkey switches to the next palette.
+	CyclePaletteHotkey Hotkey = "cyclePalette"
+
+	// FullscreenHotkey switches between fullscreen and windowed mode.
+	FullscreenHotkey Hotkey = "fullscreen"
+
+	// ToggleEffectsHotkey switches the post-processing effects on and off.
+	ToggleEffectsHotkey Hotkey = "toggleEffects"
+
+	// PauseHotkey pauses and resumes the emulation.
+	PauseHotkey Hotkey = "pause"
+
+	// ResetHotkey restarts the rom.
+	ResetHotkey Hotkey = "reset"
+
+	// FrameAdvanceHotkey pauses the emulation and emulates a single frame.
+	FrameAdvanceHotkey Hotkey = "frameAdvance"
+
+	// FastForwardHotkey speeds the emulation up while it is held.
+	FastForwardHotkey Hotkey = "fastForward"
+
+	// SlowMotionHotkey switches slow motion on and off.
+	SlowMotionHotkey Hotkey = "slowMotion"
+
+	// SpeedUpHotkey increases the tickrate.
+	SpeedUpHotkey Hotkey = "speedUp"
+
+	// SpeedDownHotkey decreases the tickrate.
+	SpeedDownHotkey Hotkey = "speedDown"
+)
+
+// hotkeyDescriptions describes every hotkey for the controls help. It also lists the valid hotkeys.
+var hotkeyDescriptions = map[Hotkey]string{
+	RecordAudioHotkey:   "start/stop recording the audio to a WAV file",
+	RecordGIFHotkey:     "start/stop recording the display to an animated GIF",
+	ScreenshotHotkey:    "save a screenshot to a PNG file",
+	CyclePaletteHotkey:  "switch to the next palette",
+	FullscreenHotkey:    "switch between fullscreen and windowed mode",
+	ToggleEffectsHotkey: "switch the effects on and off",
+	PauseHotkey:         "pause/resume",
+	ResetHotkey:         "restart the rom",
+	FrameAdvanceHotkey:  "pause and emulate a single frame",
+	FastForwardHotkey:   "fast forward while held",
+	SlowMotionHotkey:    "switch slow motion on and off",
+	SpeedUpHotkey:       "increase the speed",
+	SpeedDownHotkey:     "decrease the speed",
+}
+
+// Hotkeys maps keys to the actions they are bound to.
+type Hotkeys map[sdl.Keycode]Hotkey
+
+// DefaultHotkeys returns the default hotkeys.
+func DefaultHotkeys() Hotkeys {
+	return Hotkeys{
+		sdl.K_F9:     RecordAudioHotkey,
+		sdl.K_F10:    RecordGIFHotkey,
+		sdl.K_F12:    ScreenshotHotkey,
+		sdl.K_F1:     PauseHotkey,
+		sdl.K_F2:     ResetHotkey,
+		sdl.K_F3:     FrameAdvanceHotkey,
+		sdl.K_TAB:    FastForwardHotkey,
+		sdl.K_F4:     SlowMotionHotkey,
+		sdl.K_EQUALS: SpeedUpHotkey,
+		sdl.K_MINUS:  SpeedDownHotkey,
+		sdl.K_F6:     ToggleEffectsHotkey,
+		sdl.K_F8:     CyclePaletteHotkey,
+		sdl.K_F11:    FullscreenHotkey,
+	}
+}
+
+// Bind binds the hotkeys in bindings, which maps the names of the hotkeys to SDL key names such as "F5" or "P".
+// The previous key of a hotkey is unbound. Binding a key that is still bound to another hotkey is an error.
+func (h Hotkeys) Bind(bindings map[string]string) error {
+	keys := map[Hotkey]sdl.Keycode{}
+	for name, keyName := range bindings {
+		hotkey := Hotkey(name)
+		if _, ok := hotkeyDescriptions[hotkey]; !ok {
+			return fmt.Errorf("unknown hotkey %q", name)
+		}
+
+		key := sdl.GetKeyFromName(keyName)
+		if key == sdl.K_UNKNOWN {
+			return fmt.Errorf("unknown key %q for hotkey %s", keyName, name)
+		}
+		keys[hotkey] = key
+	}
+
+	// the hotkeys are unbound first, so that hotkeys can swap their keys.
+	for boundKey, bound := range h {
+		if _, ok := keys[bound]; ok {
+			delete(h, boundKey)
+		}
+	}
+
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hotkey, key := Hotkey(name), keys[Hotkey(name)]
+		if bound, ok := h[key]; ok {
+			return fmt.Errorf("the key %s of hotkey %s is already bound to hotkey %s", sdl.GetKeyName(key), name, bound)
+		}
+		h[key] = hotkey
+	}
+	return nil
+}
+
+// Help returns a description of the hotkeys.
+func (h Hotkeys) Help() string {
+	var lines []string
+	for key, hotkey := range h {
+		lines = append(lines, fmt.Sprintf("    %s: %s", sdl.GetKeyName(key), hotkeyDescriptions[hotkey]))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,292 @@
+package ch8sdl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// keypadLayout is the layout of the keys on the chip-8 keypad.
+var keypadLayout = [4][4]byte{
+	{0x1, 0x2, 0x3, 0xC},
+	{0x4, 0x5, 0x6, 0xD},
+	{0x7, 0x8, 0x9, 0xE},
+	{0xA, 0x0, 0xB, 0xF},
+}
+
+// defaultLayout is the keys bound to the chip-8 keypad by default. Scancodes are used so that the keys are the
+// same physical keys regardless of the keyboard layout, 1234/QWER/ASDF/ZXCV on a QWERTY keyboard.
+var defaultLayout = [4][4]sdl.Scancode{
+	{sdl.SCANCODE_1, sdl.SCANCODE_2, sdl.SCANCODE_3, sdl.SCANCODE_4},
+	{sdl.SCANCODE_Q, sdl.SCANCODE_W, sdl.SCANCODE_E, sdl.SCANCODE_R},
+	{sdl.SCANCODE_A, sdl.SCANCODE_S, sdl.SCANCODE_D, sdl.SCANCODE_F},
+	{sdl.SCANCODE_Z, sdl.SCANCODE_X, sdl.SCANCODE_C, sdl.SCANCODE_V},
+}
+
+// controllerPrefix is the prefix of the names of game controller inputs in key bindings.
+const controllerPrefix = "Pad "
+
+// axisThreshold is how far a controller axis has to be pushed for its direction to count as pressed.
+const axisThreshold = 16000
+
+// controllerInput is a button or one direction of an axis of a game controller.
+type controllerInput struct {
+	button sdl.GameControllerButton
+	axis   sdl.GameControllerAxis
+
+	// direction is 1 or -1 for axes and 0 for buttons.
+	direction int8
+}
+
+func buttonInput(button sdl.GameControllerButton) controllerInput {
+	return controllerInput{button: button, axis: sdl.CONTROLLER_AXIS_INVALID}
+}
+
+func axisInput(axis sdl.GameControllerAxis, direction int8) controllerInput {
+	return controllerInput{button: sdl.CONTROLLER_BUTTON_INVALID, axis: axis, direction: direction}
+}
+
+// defaultControllerBindings is the controller inputs bound to the chip-8 keypad by default. The d-pad and the left
+// stick are bound to 5/7/8/9, the keys under WASD, which many games use for movement.
+var defaultControllerBindings = map[controllerInput]byte{
+	buttonInput(sdl.CONTROLLER_BUTTON_DPAD_UP):    0x5,
+	buttonInput(sdl.CONTROLLER_BUTTON_DPAD_LEFT):  0x7,
+	buttonInput(sdl.CONTROLLER_BUTTON_DPAD_DOWN):  0x8,
+	buttonInput(sdl.CONTROLLER_BUTTON_DPAD_RIGHT): 0x9,
+	axisInput(sdl.CONTROLLER_AXIS_LEFTY, -1):      0x5,
+	axisInput(sdl.CONTROLLER_AXIS_LEFTX, -1):      0x7,
+	axisInput(sdl.CONTROLLER_AXIS_LEFTY, 1):       0x8,
+	axisInput(sdl.CONTROLLER_AXIS_LEFTX, 1):       0x9,
+	buttonInput(sdl.CONTROLLER_BUTTON_A):          0x6,
+	buttonInput(sdl.CONTROLLER_BUTTON_B):          0x4,
+}
+
+// KeyMap maps the keyboard keys and the game controller inputs to the keys of the chip-8 keypad.
+type KeyMap struct {
+	keys       map[sdl.Scancode]byte
+	controller map[controllerInput]byte
+}
+
+// DefaultKeyMap returns the default key map.
+func DefaultKeyMap() KeyMap {
+	keyMap := KeyMap{
+		keys:       map[sdl.Scancode]byte{},
+		controller: map[controllerInput]byte{},
+	}
+	for row := range keypadLayout {
+		for column, key := range keypadLayout[row] {
+			keyMap.keys[defaultLayout[row][column]] = key
+		}
+	}
+	for input, key := range defaultControllerBindings {
+		keyMap.controller[input] = key
+	}
+	return keyMap
+}
+
+// KeyBindings maps the keys of the chip-8 keypad, written as hex digits, to the names of the keyboard keys and
+// controller inputs that are bound to them.
+// Keyboard key names are SDL scancode names such as "Q", "Space", "Left" or "Keypad 8". Controller inputs are
+// written as "Pad " followed by an SDL game controller button name such as "a", "start" or "dpup", or an axis
+// name and a direction such as "leftx+" or "lefty-".
+type KeyBindings map[string][]string
+
+// KeyConfig is the key binding configuration of the emulator.
+// Bindings in Default replace the default bindings of the keypad keys they contain, and bindings in ROMs replace
+// the bindings of a single rom in the same way. ROMs is keyed by the SHA-1 hash of the rom. Keyboard and controller
+// bindings are replaced separately, so binding a keypad key to a keyboard key keeps its controller bindings.
+type KeyConfig struct {
+	Default KeyBindings            `json:"default"`
+	ROMs    map[string]KeyBindings `json:"roms"`
+
+	// Database holds the bindings of the rom found in the rom database. They are added after Default and before
+	// the bindings in ROMs without removing any other bindings.
+	Database KeyBindings `json:"-"`
+}
+
+// databaseButtons maps the names of the buttons in the rom database to the keys and controller inputs bound for them.
+var databaseButtons = map[string][]string{
+	"up":    {"Up", "Pad dpup", "Pad lefty-"},
+	"down":  {"Down", "Pad dpdown", "Pad lefty+"},
+	"left":  {"Left", "Pad dpleft", "Pad leftx-"},
+	"right": {"Right", "Pad dpright", "Pad leftx+"},
+	"a":     {"Space", "Pad a"},
+	"b":     {"Pad b"},
+}
+
+// DatabaseKeyBindings converts the keys of a rom in the rom database, which map button names to keypad keys, to key
+// bindings. The arrow keys, space, the d-pad, the left stick and the A and B buttons are bound.
+func DatabaseKeyBindings(keys map[string]int) KeyBindings {
+	bindings := KeyBindings{}
+	for button, keypadKey := range keys {
+		names, ok := databaseButtons[button]
+		if !ok || keypadKey < 0 || keypadKey > 0xF {
+			continue
+		}
+		key := fmt.Sprintf("%X", keypadKey)
+		bindings[key] = append(bindings[key], names...)
+	}
+	return bindings
+}
+
+// KeyMap returns the key map for the rom with the given SHA-1 hash.
+func (c KeyConfig) KeyMap(romHash string) (KeyMap, error) {
+	keyMap := DefaultKeyMap()
+	if err := keyMap.apply(c.Default, true); err != nil {
+		return KeyMap{}, err
+	}
+	if err := keyMap.apply(c.Database, false); err != nil {
+		return KeyMap{}, fmt.Errorf("rom database: %v", err)
+	}
+	if err := keyMap.apply(c.ROMs[romHash], true); err != nil {
+		return KeyMap{}, fmt.Errorf("rom %s: %v", romHash, err)
+	}
+	return keyMap, nil
+}
+
+// apply binds the keys in bindings. If replace is true, the other bindings of the keypad keys present in bindings
+// are removed.
+func (k KeyMap) apply(bindings KeyBindings, replace bool) error {
+	for keypadKey, names := range bindings {
+		key, err := strconv.ParseUint(keypadKey, 16, 4)
+		if err != nil {
+			return fmt.Errorf("invalid keypad key %q", keypadKey)
+		}
+
+		keys := map[sdl.Scancode]byte{}
+		controller := map[controllerInput]byte{}
+		for _, name := range names {
+			if strings.HasPrefix(name, controllerPrefix) {
+				input, err := parseControllerInput(strings.TrimPrefix(name, controllerPrefix))
+				if err != nil {
+					return fmt.Errorf("%v for keypad key %s", err, keypadKey)
+				}
+				controller[input] = byte(key)
+				continue
+			}
+
+			scancode := sdl.GetScancodeFromName(name)
+			if scancode == sdl.SCANCODE_UNKNOWN {
+				return fmt.Errorf("unknown key %q for keypad key %s", name, keypadKey)
+			}
+			keys[scancode] = byte(key)
+		}
+
+		if replace && (len(keys) > 0 || len(names) == 0) {
+			removeBindings(k.keys, byte(key))
+		}
+		if replace && (len(controller) > 0 || len(names) == 0) {
+			removeBindings(k.controller, byte(key))
+		}
+
+		for scancode, key := range keys {
+			k.keys[scancode] = key
+		}
+		for input, key := range controller {
+			k.controller[input] = key
+		}
+	}
+	return nil
+}
+
+// removeBindings removes the bindings of a keypad key.
+func removeBindings[T comparable](bindings map[T]byte, keypadKey byte) {
+	for input, bound := range bindings {
+		if bound == keypadKey {
+			delete(bindings, input)
+		}
+	}
+}
+
+// parseControllerInput parses the name of a controller button or an axis direction.
+func parseControllerInput(name string) (controllerInput, error) {
+	if button := sdl.GameControllerGetButtonFromString(name); button != sdl.CONTROLLER_BUTTON_INVALID {
+		return buttonInput(button), nil
+	}
+
+	if len(name) > 1 {
+		direction := int8(0)
+		switch name[len(name)-1] {
+		case '+':
+			direction = 1
+		case '-':
+			direction = -1
+		}
+
+		axis := sdl.GameControllerGetAxisFromString(name[:len(name)-1])
+		if direction != 0 && axis != sdl.CONTROLLER_AXIS_INVALID {
+			return axisInput(axis, direction), nil
+		}
+	}
+
+	return controllerInput{}, fmt.Errorf("unknown controller input %q", name)
+}
+
+// name returns the name of a controller input as written in key bindings.
+func (c controllerInput) name() string {
+	if c.direction == 0 {
+		return sdl.GameControllerGetStringForButton(c.button)
+	}
+
+	direction := "+"
+	if c.direction < 0 {
+		direction = "-"
+	}
+	return sdl.GameControllerGetStringForAxis(c.axis) + direction
+}
+
+// keyNames returns the sorted names of the keyboard keys bound to a keypad key.
+func (k KeyMap) keyNames(keypadKey byte) []string {
+	var names []string
+	for scancode, key := range k.keys {
+		if key == keypadKey {
+			names = append(names, sdl.GetScancodeName(scancode))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// controllerNames returns the sorted names of the controller inputs bound to a keypad key.
+func (k KeyMap) controllerNames(keypadKey byte) []string {
+	var names []string
+	for input, key := range k.controller {
+		if key == keypadKey {
+			names = append(names, input.name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Help returns a description of the key map in the layout of the chip-8 keypad.
+func (k KeyMap) Help() string {
+	var help strings.Builder
+	help.WriteString("    CHIP-8\t\t\tKeyboard\n")
+	for _, row := range keypadLayout {
+		help.WriteString("\t")
+		for _, key := range row {
+			fmt.Fprintf(&help, "|%X| ", key)
+		}
+		help.WriteString("\t\t")
+		for _, key := range row {
+			names := k.keyNames(key)
+			if len(names) == 0 {
+				names = []string{" "}
+			}
+			fmt.Fprintf(&help, "|%s| ", strings.Join(names, "/"))
+		}
+		help.WriteString("\n")
+	}
+
+	help.WriteString("\n    Controller\n")
+	for key := byte(0); key < 16; key++ {
+		if names := k.controllerNames(key); len(names) > 0 {
+			fmt.Fprintf(&help, "\t|%X| %s\n", key, strings.Join(names, ", "))
+		}
+	}
+	return help.String()
+}
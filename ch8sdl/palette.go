@@ -0,0 +1,147 @@
+package ch8sdl
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+// CustomPalette is the name of the palettes that are not presets or that change the colors of a preset.
+const CustomPalette = "custom"
+
+// presets holds the colors of the built in palettes in the order of the Palette fields: background, foreground,
+// second foreground and blend. The four color palettes are the themes of Octo.
+var presets = map[string][]string{
+	"black":  {"#000000", "#ffffff"},
+	"yellow": {"#9a6601", "#ffcc01"},
+	"green":  {"#000000", "#00ff00"},
+	"amber":  {"#1a0f00", "#ffb000", "#996a00", "#ffd966"},
+	"octo":   {"#996600", "#ffcc00", "#ff6600", "#662200"},
+	"lcd":    {"#f9ffb3", "#3d8026", "#abcc47", "#00131a"},
+	"hotdog": {"#000000", "#ff0000", "#ffff00", "#ffffff"},
+	"gray":   {"#aaaaaa", "#000000", "#ffffff", "#666666"},
+	"cga":    {"#000000", "#ff00ff", "#00ffff", "#ffffff"},
+}
+
+// Palette represents the colors the display is drawn with. XO-Chip draws to two bit planes, so a pixel has one
+// of four colors depending on the planes it is set in. The planes are not emulated yet, so the display is only drawn
+// with the background and the foreground for now.
+type Palette struct {
+	// Name is the name of the preset the palette was created from, or CustomPalette.
+	Name string
+
+	Background color.RGBA
+
+	// Foreground is the color of the pixels set in the first plane. Only this color is used outside XO-Chip.
+	Foreground color.RGBA
+
+	// Foreground2 is the color of the pixels set in the second plane.
+	Foreground2 color.RGBA
+
+	// Blend is the color of the pixels set in both planes.
+	Blend color.RGBA
+}
+
+// ParsePalette takes the name of a preset passed by the user and returns its palette.
+func ParsePalette(name string) (Palette, error) {
+	colors, ok := presets[name]
+	if !ok {
+		return Palette{}, fmt.Errorf("unknown palette %q, the palettes are %s", name, strings.Join(PresetNames(), ", "))
+	}
+
+	palette, err := PaletteFromColors(colors)
+	palette.Name = name
+	return palette, err
+}
+
+// PresetNames returns the sorted names of the preset palettes.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PaletteFromColors creates a palette from colors written as #rrggbb, starting with the background. Two colors
+// set the background and the foreground, which is then used for all the planes. Four colors set all the colors
+// of the palette, any further colors are ignored.
+func PaletteFromColors(colors []string) (Palette, error) {
+	palette := Palette{Name: CustomPalette}
+	if len(colors) != 2 && len(colors) < 4 {
+		return palette, fmt.Errorf("a palette needs 2 or 4 colors, got %d", len(colors))
+	}
+
+	fields := []*color.RGBA{&palette.Background, &palette.Foreground, &palette.Foreground2, &palette.Blend}
+	for i := 0; i < len(fields) && i < len(colors); i++ {
+		c, err := ParseHexColor(colors[i])
+		if err != nil {
+			return palette, err
+		}
+		*fields[i] = c
+	}
+
+	if len(colors) == 2 {
+		palette.Foreground2, palette.Blend = palette.Foreground, palette.Foreground
+	}
+	return palette, nil
+}
+
+// Color returns the color of a pixel, where planes holds a bit for each plane the pixel is set in.
+func (p Palette) Color(planes byte) color.RGBA {
+	switch planes & 3 {
+	case 1:
+		return p.Foreground
+	case 2:
+		return p.Foreground2
+	case 3:
+		return p.Blend
+	default:
+		return p.Background
+	}
+}
+
+// paletteCycle is the list of palettes the palette hotkey switches between. It starts with the palette the
+// emulator was started with, followed by the presets.
+type paletteCycle struct {
+	palettes []Palette
+	current  int
+}
+
+// newPaletteCycle creates a paletteCycle that starts with the provided palette.
+func newPaletteCycle(start Palette) *paletteCycle {
+	cycle := &paletteCycle{palettes: []Palette{start}}
+	for _, name := range PresetNames() {
+		if name == start.Name {
+			continue
+		}
+		palette, err := ParsePalette(name)
+		if err != nil {
+			panic(err) // the presets are valid
+		}
+		cycle.palettes = append(cycle.palettes, palette)
+	}
+	return cycle
+}
+
+// next switches to the next palette and returns it.
+func (c *paletteCycle) next() Palette {
+	c.current = (c.current + 1) % len(c.palettes)
+	return c.palettes[c.current]
+}
+
+// ParseHexColor parses a color written in the #rrggbb form.
+func ParseHexColor(s string) (color.RGBA, error) {
+	c := color.RGBA{A: colorAlpha}
+	if len(s) != 7 || s[0] != '#' {
+		return c, fmt.Errorf("invalid color %q, colors are written as #rrggbb", s)
+	}
+
+	_, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &c.R, &c.G, &c.B)
+	if err != nil {
+		return c, fmt.Errorf("invalid color %q, colors are written as #rrggbb", s)
+	}
+	return c, nil
+}
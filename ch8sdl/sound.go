@@ -1,19 +1,81 @@
 package ch8sdl
 
-import "github.com/veandco/go-sdl2/mix"
+import (
+	"log"
+
+	"github.com/efeckgz/GoCh8/ch8"
+	"github.com/veandco/go-sdl2/mix"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	// beepChannel is the mixer channel of the beep and sampleChannel the channel of the samples of MegaChip, so
+	// that stopping one does not stop the other.
+	beepChannel   = 0
+	sampleChannel = 1
+)
 
 type sound struct {
 	chunk *mix.Chunk
+
+	// sample is the chunk of the last MegaChip sample, which is freed when the next one is played.
+	sample *mix.Chunk
 }
 
-func newSound(chunk *mix.Chunk) sound {
-	return sound{chunk: chunk}
+func newSound(chunk *mix.Chunk) *sound {
+	return &sound{chunk: chunk}
+}
+
+func (s *sound) Play() {
+	if mix.Playing(beepChannel) == 0 {
+		s.chunk.Play(beepChannel, 0)
+	}
+}
+
+func (s *sound) Pause() {
+	mix.HaltChannel(beepChannel)
+}
+
+// PlaySample plays a sample of 8-bit unsigned samples at the provided sample rate, once or in a loop.
+func (s *sound) PlaySample(samples []byte, rate int, loop bool) {
+	s.StopSample()
+	if len(samples) == 0 || rate == 0 {
+		return
+	}
+
+	rw, err := sdl.RWFromMem(ch8.SampleWAV(samples, rate))
+	if err != nil {
+		log.Printf("Could not play sample: %v", err)
+		return
+	}
+	chunk, err := mix.LoadWAVRW(rw, true)
+	if err != nil {
+		log.Printf("Could not play sample: %v", err)
+		return
+	}
+	s.sample = chunk
+
+	loops := 0
+	if loop {
+		loops = -1
+	}
+	s.sample.Play(sampleChannel, loops)
 }
 
-func (s sound) Play() {
-	s.chunk.Play(-1, 0)
+// StopSample stops the sample and frees its chunk.
+func (s *sound) StopSample() {
+	mix.HaltChannel(sampleChannel)
+	if s.sample != nil {
+		s.sample.Free()
+		s.sample = nil
+	}
 }
 
-func (s sound) Pause() {
-	mix.HaltChannel(-1)
+// pauseSample pauses or resumes the sample while the emulation is paused.
+func (s *sound) pauseSample(paused bool) {
+	if paused {
+		mix.Pause(sampleChannel)
+	} else {
+		mix.Resume(sampleChannel)
+	}
 }
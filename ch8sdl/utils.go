@@ -6,100 +6,239 @@ import (
 	_ "embed"
 	"fmt"
 	"log"
-	"time"
+	"path/filepath"
+	"strings"
 
 	"github.com/veandco/go-sdl2/mix"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 const (
-	windowWidth  = 640
-	windowHeight = 320
+	// DefaultScale is the default size of a lores pixel in the window.
+	DefaultScale = 10
 
 	colorAlpha = 255
-)
 
-var (
-	bgR byte = 0
-	bgG byte = 0
-	bgB byte = 0
-	fgR byte = 255
-	fgG byte = 255
-	fgB byte = 255
+	// keyQueueSize is the number of key events and hotkeys that can be queued for the emulator before the main
+	// thread waits for it.
+	keyQueueSize = 64
 )
 
+// Options holds the settings of the SDL frontend.
+type Options struct {
+	// Palette is the colors the display is drawn with.
+	Palette Palette
+
+	// Keys describes how the keyboard keys and controller inputs are bound to the keypad.
+	Keys KeyConfig
+
+	// Hotkeys is the keys bound to the actions of the emulator.
+	Hotkeys Hotkeys
+
+	// Scale is the size of a lores pixel when the window is opened. The window is opened 64*Scale pixels wide and
+	// 32*Scale pixels high, and can be resized afterwards.
+	Scale int
+
+	// Volume is the volume of the beep between 0 and 100.
+	Volume int
+
+	// FlickerFilter is the filter used to hide the flicker of sprites that are drawn again every frame.
+	FlickerFilter FlickerFilter
+
+	// PhosphorFrames is the number of frames it takes the PhosphorFilter to fade a pixel out.
+	PhosphorFrames int
+
+	// Effects is the post-processing effects the display is drawn with.
+	Effects Effects
+}
+
+// DefaultOptions returns the default options.
+func DefaultOptions() Options {
+	return Options{
+		Palette: mustParsePalette("green"),
+		Hotkeys: DefaultHotkeys(),
+		Scale:   DefaultScale,
+		Volume:  100,
+
+		PhosphorFrames: DefaultPhosphorFrames,
+	}
+}
+
+// mustParsePalette returns the preset palette with the provided name.
+func mustParsePalette(name string) Palette {
+	palette, err := ParsePalette(name)
+	if err != nil {
+		panic(err)
+	}
+	return palette
+}
+
+// windowWidth returns the width of the window.
+func (o Options) windowWidth() int {
+	return 64 * o.Scale
+}
+
+// windowHeight returns the height of the window.
+func (o Options) windowHeight() int {
+	return 32 * o.Scale
+}
+
 //go:embed assets/beep.wav
 var beepBytes []byte
 
-// RunSDL runs the emulator using SDL.
-func RunSDL(spec ch8.Spec, romPath string, color ColorScheme, speed int) {
-	if color == Yellow {
-		bgR = 154
-		bgG = 102
-		bgB = 1
-		fgR = 255
-		fgG = 204
-		fgB = 1
-	}
+// frontend is the state of a session of the SDL frontend. It runs on the main thread, which SDL requires for the
+// window and the events, while the emulator runs on its own goroutine.
+type frontend struct {
+	options Options
 
-	if color == Green {
-		fgR = 0
-		fgG = 255
-		fgB = 0
+	window   *sdl.Window
+	display  *display
+	palettes *paletteCycle
+	emulator *emulator
+
+	// title is the title of the window without the status of the emulation, and status is the status shown in it.
+	title, status string
+
+	// redraw is raised when the display has to be drawn again.
+	redraw bool
+}
+
+// RunSDL runs the emulator using SDL.
+// If playback is not nil, the keypad is driven by the movie until it ends and the settings of the movie are used
+// instead of settings. If recordPath is not empty, the input of the session is recorded to a movie file in that path.
+// The emulation runs on its own goroutine at 60 frames per second, so that drawing the window does not slow it down.
+func RunSDL(settings ch8.Settings, romPath string, options Options, playback *ch8.Movie, recordPath string) {
+	if playback != nil {
+		settings = playback.Settings
 	}
 
 	// renderer is used to draw the cpu's display buffer. window is only used for cleaning up.
-	renderer, window, beep := setup(spec)
+	renderer, window, beep := setup(settings.Spec, options)
 	defer cleanup(window, renderer, beep)
 
+	display, err := newDisplay(renderer, options)
+	if err != nil {
+		log.Fatalf("Could not create the display: %v", err)
+	}
+	defer display.destroy()
+
 	sound := newSound(beep) // convert the *mix.Chunk to a Beep interface
-	cpu := ch8.NewCPU(spec, sound)
-	err := cpu.LoadProgram(romPath)
+	e := &emulator{
+		settings:    settings,
+		romPath:     romPath,
+		options:     options,
+		sound:       sound,
+		recorder:    ch8.NewAudioRecorder(sound),
+		gifRecorder: ch8.NewGIFRecorder(options.windowWidth(), options.Palette.Background, options.Palette.Foreground),
+		levels:      newPixelLevels(options.FlickerFilter, options.PhosphorFrames),
+		playback:    playback,
+		keys:        make(chan keyEvent, keyQueueSize),
+		commands:    make(chan command, keyQueueSize),
+		changed:     true,
+	}
+
+	e.cpu, err = e.newCPU()
 	if err != nil {
 		log.Fatalf("Error loading program: %v\n", err)
 	}
 
-	fmt.Println(`controls:
-    Keyboard				CHIP-8
-	|1| |2| |3| |4|			|1| |2|	|3| |C|
-	|Q| |W| |E| |R|			|4| |5| |6| |D|
-	|A| |S| |D| |F|			|7| |8| |9| |E|
-	|Z| |X| |C| |V|			|A| |0| |B| |F|`)
+	e.startTickrate = e.cpu.Tickrate
+
+	if playback != nil {
+		if err := playback.Check(&e.cpu); err != nil {
+			log.Fatalf("Can not play the movie back: %v", err)
+		}
+	}
+
+	if recordPath != "" {
+		e.movie = ch8.NewMovie(&e.cpu, settings)
+	}
+
+	keyMap, err := options.Keys.KeyMap(e.cpu.ROMHash())
+	if err != nil {
+		log.Fatalf("Invalid key configuration: %v", err)
+	}
+
+	fmt.Println("controls:")
+	fmt.Println(keyMap.Help())
+	fmt.Println(options.Hotkeys.Help())
+
+	controllers := map[sdl.JoystickID]*sdl.GameController{}
+	defer func() {
+		for _, controller := range controllers {
+			controller.Close()
+		}
+	}()
+
+	// axes holds the axis directions that are pushed past axisThreshold.
+	axes := map[controllerInput]bool{}
+
+	f := &frontend{
+		options:  options,
+		window:   window,
+		display:  display,
+		palettes: newPaletteCycle(options.Palette),
+		emulator: e,
+		title:    window.GetTitle(),
+	}
+
+	quit, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		e.run(quit)
+		close(done)
+	}()
 
 	running := true
 	for running {
-		frameStart := time.Now()
-
 		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch e := event.(type) {
+			switch ev := event.(type) {
 			case *sdl.QuitEvent:
 				running = false
+			case *sdl.WindowEvent:
+				if ev.Event == sdl.WINDOWEVENT_SIZE_CHANGED || ev.Event == sdl.WINDOWEVENT_EXPOSED {
+					f.redraw = true
+				}
+			case *sdl.ControllerDeviceEvent:
+				handleControllerDevice(ev, controllers)
+			case *sdl.ControllerButtonEvent:
+				handleControllerButton(ev, e.keys, keyMap)
+			case *sdl.ControllerAxisEvent:
+				handleControllerAxis(ev, e.keys, keyMap, axes)
 			case *sdl.KeyboardEvent:
-				handleKeyboardInput(e, &cpu)
+				handleKeyboardInput(ev, e.keys, keyMap)
+				if hotkey, ok := options.Hotkeys[ev.Keysym.Sym]; ok && ev.Repeat == 0 {
+					f.handleHotkey(hotkey, ev.State == sdl.PRESSED)
+				}
 			}
 		}
 
-		cpu.Tick(speed)
-		if cpu.DisplayUpdated {
-			drawFromBuffer(cpu.DisplayBuffer, cpu.RenderingMode, renderer, bgR, bgG, bgB, fgR, fgG, fgB)
-			cpu.DisplayUpdated = false
+		if e.frames.take(&f.display.frame) {
+			f.updateTitle()
+			f.redraw = true
 		}
 
-		frameTime := time.Since(frameStart)
-		if ch8.FrameDelay > frameTime.Milliseconds() {
-			sdl.Delay(uint32(ch8.FrameDelay - frameTime.Milliseconds())) // better than time.Sleep()
+		if f.redraw {
+			drawDisplay(f.display, f.options)
+			f.redraw = false
 		}
+
+		// the emulator paces itself, the main thread only has to keep up with the events and the frames.
+		sdl.Delay(1)
 	}
+
+	close(quit)
+	<-done
+	e.finish(recordPath)
 }
 
 // setup is a function that sets up a SDL window, renderer and the beeper for use in chip8.
-func setup(spec ch8.Spec) (*sdl.Renderer, *sdl.Window, *mix.Chunk) {
+func setup(spec ch8.Spec, options Options) (*sdl.Renderer, *sdl.Window, *mix.Chunk) {
 	beepRWops, err := sdl.RWFromMem(beepBytes)
 	if err != nil {
 		log.Fatalln("Could not read the beep binary: ", err)
 	}
 
-	err = sdl.Init(sdl.INIT_VIDEO | sdl.INIT_AUDIO | sdl.INIT_EVENTS)
+	err = sdl.Init(sdl.INIT_VIDEO | sdl.INIT_AUDIO | sdl.INIT_EVENTS | sdl.INIT_GAMECONTROLLER)
 	if err != nil {
 		log.Fatalf("Failed to initialize sdl: %v", err)
 	}
@@ -112,18 +251,34 @@ func setup(spec ch8.Spec) (*sdl.Renderer, *sdl.Window, *mix.Chunk) {
 		specOnWindow = "Super-chip 1.1"
 	case ch8.Xo:
 		specOnWindow = "XO-Chip"
+	case ch8.Chip48:
+		specOnWindow = "CHIP-48"
+	case ch8.Super10:
+		specOnWindow = "Super-chip 1.0"
+	case ch8.SuperModern:
+		specOnWindow = "Super-chip modern"
+	case ch8.Chip8X:
+		specOnWindow = "CHIP-8X"
+	case ch8.MegaChip:
+		specOnWindow = "MegaChip"
+	case ch8.HiresChip8:
+		specOnWindow = "Hi-res CHIP-8"
 	}
 
 	window, err := sdl.CreateWindow(
 		fmt.Sprintf("Chip-8 Interpreter (%s)", specOnWindow),
 		sdl.WINDOWPOS_UNDEFINED,
 		sdl.WINDOWPOS_UNDEFINED,
-		windowWidth, windowHeight,
-		sdl.WINDOW_SHOWN)
+		int32(options.windowWidth()), int32(options.windowHeight()),
+		sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
 	if err != nil {
 		log.Fatalf("Failed to create the window: %v", err)
 	}
+	window.SetMinimumSize(64, 32)
 
+	// The display is drawn at its own resolution and scaled up to the window by the renderer. Nearest pixel
+	// sampling keeps the pixels sharp.
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "0")
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
 	if err != nil {
 		log.Fatalf("Could not create renderer for window: %v", err)
@@ -141,6 +296,7 @@ func setup(spec ch8.Spec) (*sdl.Renderer, *sdl.Window, *mix.Chunk) {
 	if err != nil {
 		log.Fatalln("Could not load wavrw: ", err)
 	}
+	mix.Volume(-1, options.Volume*mix.MAX_VOLUME/100)
 
 	return renderer, window, beep
 }
@@ -160,90 +316,162 @@ func cleanup(window *sdl.Window, renderer *sdl.Renderer, beep *mix.Chunk) {
 	sdl.Quit()
 }
 
-// handleKeyboardInput is a function that maps the SDL key events to chip8 keypad and sets the keypad key states
-// accordingly when the appropriate key is pressed.
-func handleKeyboardInput(key *sdl.KeyboardEvent, cpu *ch8.CPU) {
-	switchKeyState := func(keypadIndex uint) {
-		if key.State == sdl.PRESSED {
-			cpu.Keypad[keypadIndex] = true
-		} else if key.State == sdl.RELEASED {
-			cpu.Keypad[keypadIndex] = false
-		}
+// handleKeyboardInput is a function that maps the SDL key events to chip8 keypad using the key map and sends the
+// keypad key states to the emulator when a bound key is pressed or released.
+func handleKeyboardInput(key *sdl.KeyboardEvent, keys chan<- keyEvent, keyMap KeyMap) {
+	keypadIndex, ok := keyMap.keys[key.Keysym.Scancode]
+	if !ok || key.Repeat != 0 {
+		return
 	}
 
-	switch key.Keysym.Sym {
-	case sdl.K_1:
-		switchKeyState(0x1)
-	case sdl.K_2:
-		switchKeyState(0x2)
-	case sdl.K_3:
-		switchKeyState(0x3)
-	case sdl.K_4:
-		switchKeyState(0xC)
-	case sdl.K_q:
-		switchKeyState(0x4)
-	case sdl.K_w:
-		switchKeyState(0x5)
-	case sdl.K_e:
-		switchKeyState(0x6)
-	case sdl.K_r:
-		switchKeyState(0xD)
-	case sdl.K_a:
-		switchKeyState(0x7)
-	case sdl.K_s:
-		switchKeyState(0x8)
-	case sdl.K_d:
-		switchKeyState(0x9)
-	case sdl.K_f:
-		switchKeyState(0xE)
-	case sdl.K_z:
-		switchKeyState(0xA)
-	case sdl.K_x:
-		switchKeyState(0x0)
-	case sdl.K_c:
-		switchKeyState(0xB)
-	case sdl.K_v:
-		switchKeyState(0xF)
-	}
-}
-
-// drawFromBuffer is a function that draws the contents of the chip8's display buffer to the SDL window.
-func drawFromBuffer(displayBuffer [64][128]bool, renderingMode ch8.RenderingMode, renderer *sdl.Renderer, bgR, bgG, bgB, fgR, fgG, fgB byte) {
-	var xLimit, yLimit, pixelSize int
-	switch renderingMode {
-	case ch8.LoresRendering:
-		xLimit, yLimit = 64, 32
-		pixelSize = 10
-	case ch8.HiresRendering:
-		xLimit, yLimit = 128, 64
-		pixelSize = 5
-	}
-
-	err := renderer.SetDrawColor(bgR, bgG, bgB, colorAlpha)
-	if err != nil {
-		log.Fatalln("Could not set the drawing color to black.")
+	keys <- keyEvent{key: keypadIndex, pressed: key.State == sdl.PRESSED}
+}
+
+// handleControllerButton is a function that sends the state of the keypad key bound to a controller button.
+func handleControllerButton(button *sdl.ControllerButtonEvent, keys chan<- keyEvent, keyMap KeyMap) {
+	keypadIndex, ok := keyMap.controller[buttonInput(sdl.GameControllerButton(button.Button))]
+	if ok {
+		keys <- keyEvent{key: keypadIndex, pressed: button.State == sdl.PRESSED}
 	}
+}
 
-	err = renderer.Clear()
-	if err != nil {
-		log.Fatalln("Could not clear the rendering area with the drawing color.")
+// handleControllerAxis is a function that sends the state of the keypad keys bound to the directions of a
+// controller axis. A direction is pressed when the axis is pushed past axisThreshold. pushed holds the directions
+// that are pushed, a key is only sent when its direction crosses the threshold so that an axis resting near its
+// center does not release the keys held on the keyboard.
+func handleControllerAxis(axis *sdl.ControllerAxisEvent, keys chan<- keyEvent, keyMap KeyMap, pushed map[controllerInput]bool) {
+	for _, direction := range []int8{1, -1} {
+		input := axisInput(sdl.GameControllerAxis(axis.Axis), direction)
+		keypadIndex, ok := keyMap.controller[input]
+		if !ok {
+			continue
+		}
+
+		pressed := int(axis.Value)*int(direction) > axisThreshold
+		if pressed != pushed[input] {
+			pushed[input] = pressed
+			keys <- keyEvent{key: keypadIndex, pressed: pressed}
+		}
 	}
+}
 
-	for i := 0; i < yLimit; i++ {
-		for j := 0; j < xLimit; j++ {
-			pixel := displayBuffer[i][j]
-			if pixel {
-				err := renderer.SetDrawColor(fgR, fgG, fgB, colorAlpha)
-				if err != nil {
-					log.Fatalln("Could not set the drawing color to white.")
-				}
-				err = renderer.FillRect(&sdl.Rect{X: int32(j * pixelSize), Y: int32(i * pixelSize), W: int32(pixelSize), H: int32(pixelSize)})
-				if err != nil {
-					log.Fatalln("Could not draw the current sprite.")
-				}
-			}
+// handleControllerDevice is a function that opens the controllers that are connected and closes the ones that
+// are disconnected. SDL sends an added event for every controller that is connected at startup as well.
+func handleControllerDevice(device *sdl.ControllerDeviceEvent, controllers map[sdl.JoystickID]*sdl.GameController) {
+	switch device.Type {
+	case sdl.CONTROLLERDEVICEADDED:
+		controller := sdl.GameControllerOpen(int(device.Which))
+		if controller == nil {
+			log.Printf("Could not open the controller: %v", sdl.GetError())
+			return
+		}
+		controllers[controller.Joystick().InstanceID()] = controller
+		fmt.Printf("Controller connected: %s\n", controller.Name())
+	case sdl.CONTROLLERDEVICEREMOVED:
+		if controller, ok := controllers[device.Which]; ok {
+			controller.Close()
+			delete(controllers, device.Which)
+			fmt.Println("Controller disconnected.")
+		}
+	}
+}
+
+// handleHotkey performs the action of a hotkey. The hotkeys of the window and the display are handled on the main
+// thread, the others are sent to the emulator along with the palette in use.
+func (f *frontend) handleHotkey(hotkey Hotkey, pressed bool) {
+	switch {
+	case !pressed && hotkey != FastForwardHotkey:
+		return
+	case hotkey == CyclePaletteHotkey:
+		f.options.Palette = f.palettes.next()
+		f.redraw = true
+		fmt.Printf("Palette: %s\n", f.options.Palette.Name)
+	case hotkey == FullscreenHotkey:
+		toggleFullscreen(f.window)
+		return
+	case hotkey == ToggleEffectsHotkey:
+		if f.display.toggleEffects() == NoEffects {
+			fmt.Println("Effects off.")
+		} else {
+			fmt.Println("Effects on.")
 		}
+		f.redraw = true
+		return
 	}
 
-	renderer.Present()
+	f.emulator.commands <- command{hotkey: hotkey, pressed: pressed, palette: f.options.Palette}
+}
+
+// updateTitle shows the status of the emulation in the title of the window when it changes.
+func (f *frontend) updateTitle() {
+	status := f.display.frame.status
+	if status == f.status {
+		return
+	}
+	f.status = status
+
+	title := f.title
+	if status != "" {
+		title = fmt.Sprintf("%s - %s", f.title, status)
+	}
+	f.window.SetTitle(title)
+}
+
+// toggleFullscreen is a function that switches the window between fullscreen and windowed mode.
+func toggleFullscreen(window *sdl.Window) {
+	var flags uint32 = sdl.WINDOW_FULLSCREEN_DESKTOP
+	if window.GetFlags()&sdl.WINDOW_FULLSCREEN_DESKTOP != 0 {
+		flags = 0
+	}
+	if err := window.SetFullscreen(flags); err != nil {
+		log.Printf("Could not toggle fullscreen: %v", err)
+	}
+}
+
+// saveAudioRecording is a function that stops the audio recording and saves it to the working directory.
+func saveAudioRecording(recorder *ch8.AudioRecorder, romPath string, frame int) {
+	recorder.Stop()
+	path := outputFileName(romPath, frame, "wav")
+	if err := recorder.SaveWAV(path); err != nil {
+		log.Printf("Could not save the audio recording: %v", err)
+		return
+	}
+	fmt.Printf("Audio saved to %s\n", path)
+}
+
+// saveGIFRecording is a function that stops the GIF recording and saves it to the working directory.
+func saveGIFRecording(gifRecorder *ch8.GIFRecorder, romPath string, frame int) {
+	gifRecorder.Stop()
+	path := outputFileName(romPath, frame, "gif")
+	if err := gifRecorder.SaveGIF(path); err != nil {
+		log.Printf("Could not save the GIF recording: %v", err)
+		return
+	}
+	fmt.Printf("GIF saved to %s\n", path)
+}
+
+// saveScreenshot is a function that saves the display of the cpu to a PNG file at the size of the window.
+func saveScreenshot(cpu *ch8.CPU, romPath string, frame int, options Options) {
+	width, _ := cpu.Resolution()
+	path := outputFileName(romPath, frame, "png")
+	if err := cpu.SavePNG(path, max(1, options.windowWidth()/width), options.Palette.Background, options.Palette.Foreground); err != nil {
+		log.Printf("Could not save the screenshot: %v", err)
+		return
+	}
+	fmt.Printf("Screenshot saved to %s\n", path)
+}
+
+// outputFileName is a function that derives the name of a file created by the emulator from the name of the rom
+// and the frame it is created on.
+func outputFileName(romPath string, frame int, extension string) string {
+	romName := strings.TrimSuffix(filepath.Base(romPath), filepath.Ext(romPath))
+	return fmt.Sprintf("%s-%06d.%s", romName, frame, extension)
+}
+
+// drawDisplay is a function that draws the last frame of the display to the window. Errors are logged rather
+// than fatal, so that a failed frame does not end the session.
+func drawDisplay(display *display, options Options) {
+	if err := display.draw(options.Palette); err != nil {
+		log.Printf("Could not draw the display: %v", err)
+	}
 }
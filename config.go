@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/efeckgz/GoCh8/ch8"
+	"github.com/efeckgz/GoCh8/ch8db"
+	"github.com/efeckgz/GoCh8/ch8sdl"
+)
+
+// layer is the settings that can be given at every level of the configuration. Empty values leave the setting
+// of the previous level unchanged.
+type layer struct {
+	Spec string `json:"spec"`
+
+	// Quirks only needs to list the quirks that differ from the defaults of the spec.
+	Quirks json.RawMessage `json:"quirks"`
+
+	// Tickrate is the number of instructions run every frame. IPS is the number of instructions run every second,
+	// an alternative to Tickrate. Both can be fractional.
+	Tickrate float64 `json:"tickrate"`
+	IPS      float64 `json:"ips"`
+
+	// VIPTiming runs the instructions for as long as they take on the COSMAC VIP instead of using the tickrate.
+	VIPTiming *bool `json:"vipTiming"`
+
+	// Color is the name of a preset palette. The colors below change single colors of the palette.
+	Color       string `json:"color"`
+	Background  string `json:"background"`
+	Foreground  string `json:"foreground"`
+	Foreground2 string `json:"foreground2"`
+	Blend       string `json:"blend"`
+
+	// Flicker is the name of the flicker filter. PhosphorFrames is only used by the phosphor filter.
+	Flicker        string `json:"flicker"`
+	PhosphorFrames int    `json:"phosphorFrames"`
+
+	// Effects is a comma separated list of post-processing effects.
+	Effects string `json:"effects"`
+}
+
+// config is the configuration file of the emulator.
+type config struct {
+	layer
+
+	Scale   int               `json:"scale"`
+	Volume  *int              `json:"volume"`
+	Hotkeys map[string]string `json:"hotkeys"`
+	Keys    ch8sdl.KeyConfig  `json:"keys"`
+
+	// ROMs holds the settings of single roms, keyed by the SHA-1 hash of the rom.
+	ROMs map[string]layer `json:"roms"`
+}
+
+// session is the settings of a session, built up from the levels of the configuration in the order of
+// defaults < config file < rom database < rom settings in the config file < command line.
+type session struct {
+	settings ch8.Settings
+	options  ch8sdl.Options
+
+	// specChosen is raised when a level of the configuration sets the spec.
+	specChosen bool
+}
+
+// defaultConfigPath returns the path of the configuration file in the user's config directory.
+func defaultConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "GoCh8", "config.json"), nil
+}
+
+// loadConfig reads the configuration file in the provided path. If the file does not exist and optional is
+// true, an empty configuration is returned.
+func loadConfig(path string, optional bool) (config, error) {
+	c := config{}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, fs.ErrNotExist) && optional {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return c, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// newSession creates a session with the default settings.
+func newSession() session {
+	return session{
+		settings: ch8.Settings{
+			Spec:   ch8.Original,
+			Quirks: ch8.Original.DefaultQuirks(),
+		},
+		options: ch8sdl.DefaultOptions(),
+	}
+}
+
+// applyConfig applies the settings of the configuration file that are not specific to a rom.
+func (s *session) applyConfig(c config) error {
+	if err := s.apply(c.layer); err != nil {
+		return err
+	}
+
+	if c.Scale < 0 {
+		return fmt.Errorf("invalid scale %d, the scale must be positive", c.Scale)
+	}
+	if c.Scale > 0 {
+		s.options.Scale = c.Scale
+	}
+
+	if c.Volume != nil {
+		if *c.Volume < 0 || *c.Volume > 100 {
+			return fmt.Errorf("invalid volume %d, the volume must be between 0 and 100", *c.Volume)
+		}
+		s.options.Volume = *c.Volume
+	}
+
+	if err := s.options.Hotkeys.Bind(c.Hotkeys); err != nil {
+		return err
+	}
+
+	s.options.Keys = c.Keys
+	return nil
+}
+
+// apply applies the settings of a level of the configuration.
+func (s *session) apply(l layer) error {
+	if l.Spec != "" {
+		spec, err := ch8.ParseChip8Spec(strings.ToLower(l.Spec))
+		if err != nil {
+			return err
+		}
+		s.settings.Spec, s.settings.Quirks = spec, spec.DefaultQuirks()
+		s.specChosen = true
+	}
+
+	if len(l.Quirks) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(l.Quirks))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&s.settings.Quirks); err != nil {
+			return fmt.Errorf("invalid quirks: %v", err)
+		}
+	}
+
+	if l.Tickrate < 0 || l.IPS < 0 {
+		return errors.New("invalid tickrate, the tickrate must be positive")
+	}
+	if l.Tickrate > 0 && l.IPS > 0 {
+		return errors.New("only one of the tickrate and the ips can be set")
+	}
+	if l.Tickrate > 0 {
+		s.settings.Tickrate = l.Tickrate
+	}
+	if l.IPS > 0 {
+		s.settings.Tickrate = l.IPS / ch8.FramesPerSecond
+	}
+	if l.VIPTiming != nil {
+		s.settings.VIPTiming = *l.VIPTiming
+	}
+
+	if l.Color != "" {
+		palette, err := ch8sdl.ParsePalette(strings.ToLower(l.Color))
+		if err != nil {
+			return err
+		}
+		s.options.Palette = palette
+	}
+
+	if l.Flicker != "" {
+		filter, err := ch8sdl.ParseFlickerFilter(strings.ToLower(l.Flicker))
+		if err != nil {
+			return err
+		}
+		s.options.FlickerFilter = filter
+	}
+
+	if l.PhosphorFrames < 0 {
+		return fmt.Errorf("invalid phosphor frames %d, the number of frames must be positive", l.PhosphorFrames)
+	}
+	if l.PhosphorFrames > 0 {
+		s.options.PhosphorFrames = l.PhosphorFrames
+	}
+
+	if l.Effects != "" {
+		effects, err := ch8sdl.ParseEffects(strings.ToLower(l.Effects))
+		if err != nil {
+			return err
+		}
+		s.options.Effects = effects
+	}
+
+	return s.applyColors(l)
+}
+
+// applyColors changes the colors of the palette that are set in the level. The planes of a two color palette
+// keep using the foreground color unless they are set as well.
+func (s *session) applyColors(l layer) error {
+	palette := &s.options.Palette
+	twoColors := palette.Foreground2 == palette.Foreground && palette.Blend == palette.Foreground
+
+	colors := []struct {
+		hex   string
+		color *color.RGBA
+	}{
+		{l.Background, &palette.Background},
+		{l.Foreground, &palette.Foreground},
+		{l.Foreground2, &palette.Foreground2},
+		{l.Blend, &palette.Blend},
+	}
+	for _, c := range colors {
+		if c.hex == "" {
+			continue
+		}
+		parsed, err := ch8sdl.ParseHexColor(c.hex)
+		if err != nil {
+			return err
+		}
+		*c.color = parsed
+		palette.Name = ch8sdl.CustomPalette
+	}
+
+	if twoColors && l.Foreground2 == "" {
+		palette.Foreground2 = palette.Foreground
+	}
+	if twoColors && l.Blend == "" {
+		palette.Blend = palette.Foreground
+	}
+	return nil
+}
+
+// applyDatabaseEntry applies the settings of a rom found in the rom database.
+func (s *session) applyDatabaseEntry(entry ch8db.Entry) {
+	s.settings.Spec, s.settings.Quirks = entry.Spec, entry.Quirks
+	s.specChosen = true
+
+	if entry.Tickrate > 0 {
+		s.settings.Tickrate = entry.Tickrate
+	}
+
+	if palette, err := ch8sdl.PaletteFromColors(entry.Colors); err == nil {
+		s.options.Palette = palette
+	}
+
+	s.options.Keys.Database = ch8sdl.DatabaseKeyBindings(entry.Keys)
+}
+
+// applyDetection applies the spec and the quirks detected from the instructions of a rom. A spec set in the
+// config file is only overridden when the detection found something specific to a spec.
+func (s *session) applyDetection(detection ch8.Detection) {
+	if s.specChosen && detection.Confidence == ch8.LowConfidence {
+		return
+	}
+
+	fmt.Printf("Detected the %s spec with %s confidence: %s.\n", detection.Spec, detection.Confidence, strings.Join(detection.Reasons, ", "))
+	s.settings.Spec, s.settings.Quirks = detection.Spec, detection.Quirks
+}
@@ -1,30 +1,149 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/efeckgz/GoCh8/ch8"
+	"github.com/efeckgz/GoCh8/ch8db"
+	"github.com/efeckgz/GoCh8/ch8headless"
 	"github.com/efeckgz/GoCh8/ch8sdl"
 )
 
 func main() {
 	romPathArg := flag.String("rom", "", "Path to the chip 8 program")
-	colorArg := flag.String("color", "green", "The color scheme for Chip 8")
+	colorArg := flag.String("color", "green", "The palette for Chip 8")
+	bgArg := flag.String("bg", "", "The background color written as #rrggbb")
+	fgArg := flag.String("fg", "", "The foreground color written as #rrggbb")
+	fg2Arg := flag.String("fg2", "", "The color of the second XO-Chip plane written as #rrggbb")
+	blendArg := flag.String("blend", "", "The color of the pixels in both XO-Chip planes written as #rrggbb")
 	specArg := flag.String("spec", "original", "The specification of Chip 8 to emulate.")
-	speedArg := flag.Int("speed", 1, "The speed of emulation")
+	tickrateArg := flag.Float64("tickrate", 0, "The number of instructions run every frame. Defaults to the tickrate of the spec")
+	vipTimingArg := flag.Bool("vip-timing", false, "Run the instructions for as long as they take on the COSMAC VIP instead of using the tickrate")
+	ipsArg := flag.Float64("ips", 0, "The number of instructions run every second, an alternative to --tickrate")
+	flickerArg := flag.String("flicker", "", "The filter used to hide flicker: none, phosphor or blend")
+	phosphorFramesArg := flag.Int("phosphor-frames", 0, "The number of frames it takes the phosphor filter to fade a pixel out")
+	effectsArg := flag.String("effects", "", "Comma separated post-processing effects: scanlines, grid, bloom, curvature, crt, lcd or none")
+	scaleArg := flag.Int("scale", ch8sdl.DefaultScale, "The size of a lores pixel when the window is opened")
+	headlessArg := flag.Bool("headless", false, "Run without a window or sound for the number of frames given by --frames")
+	framesArg := flag.Int("frames", 600, "The number of frames to emulate in headless mode")
+	wavArg := flag.String("wav", "", "Path of a WAV file to record the audio of a headless run to")
+	gifArg := flag.String("gif", "", "Path of a GIF file to record the display of a headless run to")
+	recordArg := flag.String("record", "", "Path of a movie file to record the input of a windowed session to")
+	playArg := flag.String("play", "", "Path of a movie file to play back")
+	seedArg := flag.Int64("seed", 0, "The seed of the random number generator. A random seed is used if not provided")
+	vipRandomArg := flag.Bool("vip-random", false, "Generate random numbers with the algorithm of the COSMAC VIP routine, not the numbers of a real VIP")
+	configArg := flag.String("config", "", "Path of the configuration file. Defaults to GoCh8/config.json in the user config directory")
+	dbArg := flag.String("db", "", "Path of the chip-8 database directory. Defaults to GoCh8/chip-8-database in the user config directory")
 
+	flag.Parse()
 	colorArg = trimAndLower(colorArg)
 	specArg = trimAndLower(specArg)
-	flag.Parse()
 
 	checkArgumentAndAsk("Rom path", romPathArg)
-	color := ch8sdl.ParseColorScheme(colorArg)
-	spec := ch8.ParseChip8Spec(specArg)
+	rom, err := os.ReadFile(filepath.Clean(*romPathArg))
+	if err != nil {
+		log.Fatalf("Error loading program: %v\n", err)
+	}
+	romHash := ch8.HashROM(rom)
+
+	session := newSession()
+	c := readConfig(*configArg)
+	if err := session.applyConfig(c); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Known roms are configured from the database. The spec of unknown roms is detected when no other level of
+	// the configuration sets it for the rom.
+	romLayer := c.ROMs[romHash]
+	if entry, ok := lookupROM(*dbArg, romHash); ok {
+		fmt.Printf("Found %s in the rom database (%s).\n", entry.Title, entry.Platform)
+		session.applyDatabaseEntry(entry)
+	} else if romLayer.Spec == "" && !isFlagSet("spec") {
+		session.applyDetection(ch8.Detect(rom))
+	}
+
+	if err := session.apply(romLayer); err != nil {
+		log.Fatalf("Invalid configuration for the rom %s: %v", romHash, err)
+	}
+
+	if (isFlagSet("tickrate") && *tickrateArg <= 0) || (isFlagSet("ips") && *ipsArg <= 0) {
+		log.Fatalf("Invalid argument: the tickrate must be positive")
+	}
+	if isFlagSet("phosphor-frames") && *phosphorFramesArg <= 0 {
+		log.Fatalf("Invalid argument: the number of phosphor frames must be positive")
+	}
+	flags := layer{
+		Spec:        *specArg,
+		Tickrate:    *tickrateArg,
+		IPS:         *ipsArg,
+		VIPTiming:   vipTimingArg,
+		Color:       *colorArg,
+		Background:  *bgArg,
+		Foreground:  *fgArg,
+		Foreground2: *fg2Arg,
+		Blend:       *blendArg,
+
+		Flicker:        *flickerArg,
+		PhosphorFrames: *phosphorFramesArg,
+		Effects:        *effectsArg,
+	}
+	if err := session.apply(flagLayer(flags)); err != nil {
+		log.Fatalf("Invalid argument: %v", err)
+	}
+
+	if isFlagSet("scale") {
+		if *scaleArg <= 0 {
+			log.Fatalf("Invalid argument: the scale must be positive")
+		}
+		session.options.Scale = *scaleArg
+	}
+
+	settings := session.settings
+	settings.Seed, settings.VIPRandom = *seedArg, *vipRandomArg
+	if !isFlagSet("seed") {
+		settings.Seed = rand.Int63()
+	}
+
+	var playback *ch8.Movie
+	if *playArg != "" {
+		movie, err := ch8.LoadMovie(*playArg)
+		if err != nil {
+			log.Fatalf("Could not load the movie: %v", err)
+		}
+		playback = movie
+	}
+
+	if *headlessArg {
+		palette := session.options.Palette
+		ch8headless.RunHeadless(settings, *romPathArg, *framesArg, *wavArg, *gifArg, palette.Background, palette.Foreground, playback)
+		return
+	}
 
-	ch8sdl.RunSDL(spec, *romPathArg, color, *speedArg)
+	ch8sdl.RunSDL(settings, *romPathArg, session.options, playback, *recordArg)
+}
+
+// flagLayer is a function that creates a configuration level from the flags that are provided. flags holds the
+// values of all the flags, the colors are empty unless they are provided.
+func flagLayer(flags layer) layer {
+	l := flags
+	if !isFlagSet("spec") {
+		l.Spec = ""
+	}
+	if !isFlagSet("color") {
+		l.Color = ""
+	}
+	if !isFlagSet("vip-timing") {
+		l.VIPTiming = nil
+	}
+	return l
 }
 
 // trimAndLower is a function that removes whitespace from a string and converts it to lowercase.
@@ -46,3 +165,56 @@ func checkArgumentAndAsk(argumentName string, argument *string) {
 		}
 	}
 }
+
+// readConfig is a function that reads the configuration file in the provided path, or in the user config
+// directory if the path is empty. The file in the user config directory is optional.
+func readConfig(path string) config {
+	optional := path == ""
+	if optional {
+		defaultPath, err := defaultConfigPath()
+		if err != nil {
+			return config{}
+		}
+		path = defaultPath
+	}
+
+	c, err := loadConfig(path, optional)
+	if err != nil {
+		log.Fatalf("Could not load the configuration: %v", err)
+	}
+	return c
+}
+
+// lookupROM is a function that looks the rom up in the database in the provided directory, or in the user config
+// directory if the path is empty. The database in the user config directory is optional.
+func lookupROM(dbPath, romHash string) (ch8db.Entry, bool) {
+	optional := dbPath == ""
+	if optional {
+		defaultPath, err := ch8db.DefaultPath()
+		if err != nil {
+			return ch8db.Entry{}, false
+		}
+		dbPath = defaultPath
+	}
+
+	db, err := ch8db.Load(dbPath)
+	if errors.Is(err, fs.ErrNotExist) && optional {
+		return ch8db.Entry{}, false
+	}
+	if err != nil {
+		log.Fatalf("Could not load the rom database: %v", err)
+	}
+
+	return db.Lookup(romHash)
+}
+
+// isFlagSet is a function that checks if a flag was provided by the user.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}